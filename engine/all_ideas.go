@@ -36,36 +36,6 @@ This should apply to all eval terms. Above normal is a bonus, below normal is a
 --- Null Move Pruning Eval ---
 Use full eval and not just simple eval for null moves, now that other eval is more important.
 
---- Draw Detection ---
-Add detection of drawn endgames, even though we are up material.
-Split between strict draw (draw according to the rules), or drawish (tendency to be a draw).
-
-Strict draws (cannot be checkmate):
-___________________________________
-- k vs k
-- kn v k
-- kb v k
-
-Drawish (tends to be a draw):
-_____________________________
-(single queens or rooks)
-- kq vs kq
-- kr vs kr
-(minors only):
-- kn vs kn
-- kn vs kb
-- kb vs kb
-(queen vs minors):
-- kq vs krr
-- kq vs kbb
-- kq vs knn
-(rook vs minors):
-- krb vs kr
-- krn vs kr
-
---- Eval bishop pair ---
-Bonus for having the bishop pair.
-
 --- Index speedup ---
 Change the /8 and %8 indexing to just look up the row and column directly (precomputed), if it is slow.
 
@@ -80,21 +50,14 @@ Try scoring the 1st repetition as a draw and not the 2nd to save search depth?
 In the endgame, sort killer moves before good threat moves?
 The cutoff rate for those moves seem higher in the LATE endagme (stage value at most 5 or less).
 
---- SEE ---
-Recursive call line negamax where we input the bitboards,
-and only look for recaptures on a particular square.
-Once this is done, try MVV-LVA again, where "bad" captured have a SEE test.
-
 --- QS TT ---
-Add a small TT specially for QS to fit in the cache.
+QS nodes are now stored in the main TT with the qs depth.
+Test a small TT specially for QS to fit in the cache, so that QS entries don't replace entries of the main search.
 
 --- Null move ---
 Reduce depth from 6, but don't decrease the depth as much (similar to LMR).
 Also, allow null move on shallower depths straight to QS (assumes that a null move is refuted by a simple capture).
 
---- Delta pruninng (futility pruning in qs) ---
-If eval + cature piece value < alpha by a margin, just ignore (likely to fail low).
-
 --- Auto tune ---
 Add a function to be able to "modify" eval heatmaps and other parameters before engine init.
 That way it can be passed from the Python match manager.
@@ -102,10 +65,6 @@ That way it can be passed from the Python match manager.
 --- TT Size ---
 Test whether increasing the TT size helps improve play at longer time controls (vs lower cache hits)?
 
---- Draw by insufficient material ---
-Especially king vs king (eval game stage count == 0: all pieces off, then also just check pawn count == 0), king vs king and knight etc.
-Also incorporate this into the evaluation (should be given a drawish score).
-
 --- Book moves ---
 Play book moves for the first few moves.
 
@@ -141,25 +100,10 @@ Also add back queen mobility, but at say 1/2 or 1/3 of the actual mobility.
 
 This did not show a noticeable improvement.
 
---- QS Checks ---
-Rather generate all evasions in qs.
-And then cannot stand pat in qs in check.
-
-Tested but this results in an ELO loss, so test again later.
-
 --- QS Move Gen ---
 Generate only captures in qs - flag the move generator.
 
 The issue was that mobility cannot be scored in qs because we don't generate all the moves.
 So even though move gen was 50% faster, the impact on mobility evaluation was too big.
 
---- Eval King Safety ---
-Use the move generation done at each turn, and count the number of squares next to the king that are attacked,
-(we already calculate this for move generation).
-Give a penalty for each square that is attacked.
-Additionally, count the king checks as part of that score (to de-incentivise allowing checks).
-Scale this down towards the endgame.
-
-This did not show an improvement, so come back to this later.
-
 */
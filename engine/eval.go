@@ -122,7 +122,6 @@ const (
 const (
 	DOUBLED_PAWN_PENALTY  int = -5  // penalty for a pawn if there are other friendly pawns on that column
 	ISOLATED_PAWN_PENALTY int = -15 // penalty for a pawn without other friendly pawns on the 2 columns besides
-	PASSED_PAWN_BONUS     int = 15  // bonus for a pawn that has no enemy pawns in front of it in the left, middle, and right columns
 	PROTECTED_PAWN_BONUS  int = 5   // bonus for a pawn that is also directly protected by a friendly pawn behind it
 )
 
@@ -160,6 +159,8 @@ func (pos *Position) evalPosAfter() {
 
 		// set the starting eval
 		pawnStructureEval := 0
+		whitePassed := emptyBB
+		blackPassed := emptyBB
 
 		// white pawns
 		whitePawnsPop := pos.pieces[SIDE_WHITE][PIECE_PAWN]
@@ -179,10 +180,11 @@ func (pos *Position) evalPosAfter() {
 				pawnStructureEval += ISOLATED_PAWN_PENALTY
 			}
 
-			// passed pawns (if no enemy pawns on the 3 columns in front of the pawn)
-			enemyPawnsInFront := (blackPawns & pawnPassedMasks[SIDE_WHITE][pawnSq]).countBits()
-			if enemyPawnsInFront == 0 {
-				pawnStructureEval += PASSED_PAWN_BONUS
+			// passed pawns (if no enemy pawns on the 3 columns in front of the pawn, see eval_passed_pawns.go)
+			passedScore, isPassed := getPassedPawnStructureScore(SIDE_WHITE, pawnSq, whitePawns, blackPawns)
+			if isPassed {
+				pawnStructureEval += passedScore
+				whitePassed.setBit(pawnSq)
 			}
 
 			// protected pawns (if the pawn is directly protected by a friendly pawn)
@@ -210,10 +212,11 @@ func (pos *Position) evalPosAfter() {
 				pawnStructureEval -= ISOLATED_PAWN_PENALTY
 			}
 
-			// passed pawns (if no enemy pawns on the 3 columns in front of the pawn)
-			enemyPawnsInFront := (whitePawns & pawnPassedMasks[SIDE_BLACK][pawnSq]).countBits()
-			if enemyPawnsInFront == 0 {
-				pawnStructureEval -= PASSED_PAWN_BONUS
+			// passed pawns (if no enemy pawns on the 3 columns in front of the pawn, see eval_passed_pawns.go)
+			passedScore, isPassed := getPassedPawnStructureScore(SIDE_BLACK, pawnSq, blackPawns, whitePawns)
+			if isPassed {
+				pawnStructureEval += passedScore
+				blackPassed.setBit(pawnSq)
 			}
 
 			// protected pawns (if the pawn is directly protected by a friendly pawn)
@@ -227,10 +230,42 @@ func (pos *Position) evalPosAfter() {
 		pos.evalPawnHashTable[pawnHash].whitePawns = whitePawns
 		pos.evalPawnHashTable[pawnHash].blackPawns = blackPawns
 		pos.evalPawnHashTable[pawnHash].value = pawnStructureEval
+		pos.evalPawnHashTable[pawnHash].whitePassed = whitePassed
+		pos.evalPawnHashTable[pawnHash].blackPassed = blackPassed
 	}
 
 	// finally add the pawn structure eval
 	pos.evalOther += pos.evalPawnHashTable[pawnHash].value
 
+	// ------------------------------------------------- PASSED PAWNS --------------------------------------------------
+	// the passed pawns are found with the pawn structure (and stored in the pawn hash table)
+	// but blockers, rooks and king distances depend on the other pieces, so they are scored for each position
+	pos.evalOther += pos.evalPassedPawns(pos.evalPawnHashTable[pawnHash].whitePassed, pos.evalPawnHashTable[pawnHash].blackPassed)
+
+	// ------------------------------------------------- ATTACKS --------------------------------------------------
+	// we build the attack bitboards of both sides once, for the threats and king safety (see eval_threats.go)
+	attacks := pos.getEvalAttacks()
+
+	// ------------------------------------------------- PIECES --------------------------------------------------
+	// we give bonuses and penalties for the positions of bishops, knights and rooks (see eval_pieces.go)
+	pos.evalOther += pos.evalPieces()
+
+	// ------------------------------------------------- THREATS --------------------------------------------------
+	// we give bonuses for enemy pieces that are attacked by cheaper pieces or are hanging (see eval_threats.go)
+	pos.evalOther += pos.evalThreats(&attacks)
+
+	// ------------------------------------------------- KING SAFETY --------------------------------------------------
+	// we give penalties and bonuses for the pawns around the king, and the attacks on the king zone (see eval_king_safety.go)
+	pos.evalOther += pos.evalKingSafety(&attacks)
+
+	// ------------------------------------------------- DRAWISH ENDGAMES --------------------------------------------------
+	// in drawish endgames, we scale the total evaluation towards zero (see eval_endgames.go)
+	// the material and heatmap evals are updated incrementally, so we do the scaling through the other eval
+	endgameScale := pos.getEndgameScale()
+	if endgameScale != ENDGAME_SCALE_NORMAL {
+		totalEval := pos.evalMaterial + pos.evalHeatmaps + pos.evalOther
+		pos.evalOther -= totalEval - (totalEval*endgameScale)/ENDGAME_SCALE_NORMAL
+	}
+
 	pos.logTime.allLogTypes[LOG_EVAL].stop()
 }
@@ -0,0 +1,122 @@
+package main
+
+// --------------------------------------------------------------------------------------------------------------------
+// ------------------------------------------------- Endgame Recognition ----------------------------------------------
+// --------------------------------------------------------------------------------------------------------------------
+/*
+Some endgames are drawn (or tend to be drawn), even though one side is up material.
+Without recognizing them, the evaluation would claim a material edge, and the search would steer towards them.
+
+Strict draws (insufficient material, checkmate is not possible):
+- k vs k
+- kn vs k
+- kb vs k
+These are detected as a game state (STATE_DRAW_INSUFFICIENT_MATERIAL), and scored as a draw in the search.
+
+Drawish endgames (tend to be a draw, but checkmate is still possible):
+- single queens or rooks: kq vs kq, kr vs kr
+- minors only: kn vs kn, kn vs kb, kb vs kb
+- queen vs minors: kq vs krr, kq vs kbb, kq vs knn
+- rook vs minors: krb vs kr, krn vs kr
+All of these endgames are without pawns, because pawns can promote and change the endgame.
+For these endgames, we scale the evaluation towards zero (the lower the scale, the more drawish the endgame).
+The engine then still tries to win them, but prefers other positions where the material edge is more likely to win.
+*/
+
+const (
+	ENDGAME_SCALE_NORMAL int = 16 // scale of the evaluation for normal positions (no scaling)
+
+	ENDGAME_SCALE_MAJOR_VS_MAJOR int = 4 // scale for a single queen or rook on both sides
+	ENDGAME_SCALE_MINOR_VS_MINOR int = 2 // scale for a single minor piece on both sides
+	ENDGAME_SCALE_QUEEN_VS_PAIR  int = 4 // scale for a queen against two rooks or two minor pieces
+	ENDGAME_SCALE_ROOK_MINOR     int = 4 // scale for a rook and minor piece against a rook
+)
+
+// the material of one side in a pawnless endgame (the number of each piece type)
+type EndgameMaterial struct {
+	queens  int
+	rooks   int
+	knights int
+	bishops int
+}
+
+// a drawish endgame, from the point of view of the stronger side (the side with more material, or either side if equal)
+type DrawishEndgame struct {
+	strong EndgameMaterial
+	weak   EndgameMaterial
+	scale  int
+}
+
+var drawishEndgames = []DrawishEndgame{
+	// single queens or rooks
+	{EndgameMaterial{queens: 1}, EndgameMaterial{queens: 1}, ENDGAME_SCALE_MAJOR_VS_MAJOR},
+	{EndgameMaterial{rooks: 1}, EndgameMaterial{rooks: 1}, ENDGAME_SCALE_MAJOR_VS_MAJOR},
+
+	// minors only
+	{EndgameMaterial{knights: 1}, EndgameMaterial{knights: 1}, ENDGAME_SCALE_MINOR_VS_MINOR},
+	{EndgameMaterial{bishops: 1}, EndgameMaterial{knights: 1}, ENDGAME_SCALE_MINOR_VS_MINOR},
+	{EndgameMaterial{bishops: 1}, EndgameMaterial{bishops: 1}, ENDGAME_SCALE_MINOR_VS_MINOR},
+
+	// queen vs minors (or rooks)
+	{EndgameMaterial{queens: 1}, EndgameMaterial{rooks: 2}, ENDGAME_SCALE_QUEEN_VS_PAIR},
+	{EndgameMaterial{queens: 1}, EndgameMaterial{bishops: 2}, ENDGAME_SCALE_QUEEN_VS_PAIR},
+	{EndgameMaterial{queens: 1}, EndgameMaterial{knights: 2}, ENDGAME_SCALE_QUEEN_VS_PAIR},
+
+	// rook vs minors
+	{EndgameMaterial{rooks: 1, bishops: 1}, EndgameMaterial{rooks: 1}, ENDGAME_SCALE_ROOK_MINOR},
+	{EndgameMaterial{rooks: 1, knights: 1}, EndgameMaterial{rooks: 1}, ENDGAME_SCALE_ROOK_MINOR},
+}
+
+// returns true if neither side can checkmate anymore (k vs k, kn vs k, kb vs k)
+func (pos *Position) isInsufficientMaterial() bool {
+
+	// any pawn, rook or queen can still checkmate
+	majorsAndPawns := pos.pieces[SIDE_WHITE][PIECE_PAWN] | pos.pieces[SIDE_BLACK][PIECE_PAWN] |
+		pos.pieces[SIDE_WHITE][PIECE_ROOK] | pos.pieces[SIDE_BLACK][PIECE_ROOK] |
+		pos.pieces[SIDE_WHITE][PIECE_QUEEN] | pos.pieces[SIDE_BLACK][PIECE_QUEEN]
+	if majorsAndPawns != 0 {
+		return false
+	}
+
+	// a single minor piece (on either side) can't checkmate
+	minors := pos.pieces[SIDE_WHITE][PIECE_KNIGHT] | pos.pieces[SIDE_BLACK][PIECE_KNIGHT] |
+		pos.pieces[SIDE_WHITE][PIECE_BISHOP] | pos.pieces[SIDE_BLACK][PIECE_BISHOP]
+	return minors.countBits() <= 1
+}
+
+// returns the scale of the evaluation (out of ENDGAME_SCALE_NORMAL) for the material on the board
+// drawish endgames have a lower scale, all other positions have the normal scale
+func (pos *Position) getEndgameScale() int {
+
+	// all drawish endgames are without pawns
+	if pos.pieces[SIDE_WHITE][PIECE_PAWN]|pos.pieces[SIDE_BLACK][PIECE_PAWN] != 0 {
+		return ENDGAME_SCALE_NORMAL
+	}
+
+	// insufficient material is always a draw
+	if pos.isInsufficientMaterial() {
+		return 0
+	}
+
+	whiteMaterial := pos.getEndgameMaterial(SIDE_WHITE)
+	blackMaterial := pos.getEndgameMaterial(SIDE_BLACK)
+
+	// check the endgame with either side as the stronger side
+	for _, endgame := range drawishEndgames {
+		if (endgame.strong == whiteMaterial && endgame.weak == blackMaterial) || (endgame.strong == blackMaterial && endgame.weak == whiteMaterial) {
+			return endgame.scale
+		}
+	}
+
+	return ENDGAME_SCALE_NORMAL
+}
+
+// returns the number of each piece type of the side (excluding the king and pawns)
+func (pos *Position) getEndgameMaterial(side int) EndgameMaterial {
+	return EndgameMaterial{
+		queens:  pos.pieces[side][PIECE_QUEEN].countBits(),
+		rooks:   pos.pieces[side][PIECE_ROOK].countBits(),
+		knights: pos.pieces[side][PIECE_KNIGHT].countBits(),
+		bishops: pos.pieces[side][PIECE_BISHOP].countBits(),
+	}
+}
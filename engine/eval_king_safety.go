@@ -0,0 +1,163 @@
+package main
+
+// --------------------------------------------------------------------------------------------------------------------
+// ------------------------------------------------------ King Safety -------------------------------------------------
+// --------------------------------------------------------------------------------------------------------------------
+/*
+King safety scores how exposed each king is to an attack. It is made of two parts:
+
+Pawns around the king (for the king column and the columns on both sides of the king):
+- Pawn shield: a bonus for friendly pawns directly in front of the king (1 or 2 rows in front).
+- Missing shield: a penalty when there is no friendly pawn in front of the king on the column (a half-open column).
+- Open columns: an additional penalty when there are no pawns at all in front of the king on the column.
+- Pawn storm: a penalty for enemy pawns that advance towards the king (2 or 3 rows in front of the king).
+
+Attacks on the king zone (the king square and the squares next to the king):
+While building the attack bitboards for the evaluation (see eval_threats.go), we count the squares of the king zone
+attacked by each enemy queen, rook, bishop and knight, weighted by the attacking piece type (the "attack units").
+A single attacker is rarely dangerous on its own, so the attack units are only counted with at least 2 attackers.
+The penalty grows quadratically with the attack units (many attacks together are much more dangerous), and is capped.
+The attacks only depend on the board, so the same position always gets the same king safety score.
+
+King safety is only relevant in the middlegame (in the endgame the king should be active),
+so the score is tapered with the game stage, and is zero in the endgame.
+*/
+
+const (
+	// attack units for each king zone square attacked by the piece type
+	KING_ATTACK_WEIGHT_QUEEN  int = 5
+	KING_ATTACK_WEIGHT_ROOK   int = 3
+	KING_ATTACK_WEIGHT_BISHOP int = 2
+	KING_ATTACK_WEIGHT_KNIGHT int = 2
+
+	KING_ATTACK_MIN_ATTACKERS int = 2   // minimum number of attacking pieces before the attack units are counted
+	KING_ATTACK_MAX_PENALTY   int = 500 // maximum penalty for the attacks on the king zone
+
+	KING_SHIELD_PAWN_CLOSE_BONUS int = 15  // bonus for a friendly pawn 1 row in front of the king
+	KING_SHIELD_PAWN_FAR_BONUS   int = 8   // bonus for a friendly pawn 2 rows in front of the king
+	KING_SHIELD_MISSING_PENALTY  int = -20 // penalty for no friendly pawns in front of the king on a column
+	KING_OPEN_COLUMN_PENALTY     int = -15 // additional penalty for no pawns at all in front of the king on a column
+	KING_PAWN_STORM_2_PENALTY    int = -15 // penalty for an enemy pawn 2 rows in front of the king
+	KING_PAWN_STORM_3_PENALTY    int = -8  // penalty for an enemy pawn 3 rows in front of the king
+)
+
+var evalKingZoneMasks [64]Bitboard     // masks with the king square and the squares next to the king set
+var evalKingFrontMasks [2][64]Bitboard // masks with all the rows in front of the king set (for the specific side)
+
+// create the king zone and king front masks for each square
+func initEvalKingSafetyMasks() {
+	for sq := 0; sq < 64; sq++ {
+		kingZone := getKingMovesPseudo(sq)
+		kingZone.setBit(sq)
+		evalKingZoneMasks[sq] = kingZone
+
+		kingRow, _ := rowAndColFromSq(sq)
+		for row := 0; row < 8; row++ {
+			if row > kingRow {
+				evalKingFrontMasks[SIDE_WHITE][sq] |= pawnRowMasks[row]
+			} else if row < kingRow {
+				evalKingFrontMasks[SIDE_BLACK][sq] |= pawnRowMasks[row]
+			}
+		}
+	}
+}
+
+// returns the king safety score from the white side (tapered with the game stage)
+func (pos *Position) evalKingSafety(attacks *EvalAttacks) int {
+
+	// the score is only relevant in the middlegame
+	evalStage := pos.evalMidVsEndStage
+	if evalStage > STAGE_VAL_STARTING { // cap to the max stage value
+		evalStage = STAGE_VAL_STARTING
+	}
+	if evalStage == 0 {
+		return 0
+	}
+
+	// ___ Pawns Around the King ___
+	kingSafety := pos.getKingPawnsScore(SIDE_WHITE) - pos.getKingPawnsScore(SIDE_BLACK)
+
+	// ___ Attacks on the King Zone ___
+	// the white attack units are the attacks of white pieces on the black king
+	kingSafety += getKingAttackPenalty(attacks.kingZoneAttackUnits[SIDE_BLACK]) - getKingAttackPenalty(attacks.kingZoneAttackUnits[SIDE_WHITE])
+
+	return (kingSafety * evalStage) / STAGE_VAL_STARTING
+}
+
+// returns the penalty (zero or negative) for the attack units on the king zone
+func getKingAttackPenalty(attackUnits int) int {
+	penalty := (attackUnits * attackUnits) / 2
+	if penalty > KING_ATTACK_MAX_PENALTY {
+		penalty = KING_ATTACK_MAX_PENALTY
+	}
+	return 0 - penalty
+}
+
+// returns the score of the pawn shield, open columns and pawn storm around the king of the side
+func (pos *Position) getKingPawnsScore(side int) int {
+
+	enSide := SIDE_BLACK
+	if side == SIDE_BLACK {
+		enSide = SIDE_WHITE
+	}
+
+	kingSq := pos.pieces[side][PIECE_KING].getLSBSq()
+	kingRow, kingCol := rowAndColFromSq(kingSq)
+
+	// only the pawns in front of the king matter
+	frPawns := pos.pieces[side][PIECE_PAWN] & evalKingFrontMasks[side][kingSq]
+	enPawns := pos.pieces[enSide][PIECE_PAWN] & evalKingFrontMasks[side][kingSq]
+
+	score := 0
+	for col := kingCol - 1; col <= kingCol+1; col++ {
+		if col < 0 || col > 7 {
+			continue
+		}
+
+		// find the closest friendly and enemy pawns in front of the king on the column (in rows from the king, zero if none)
+		frPawnDistance := getKingPawnDistance(frPawns&pawnColumnMasks[col], side, kingRow)
+		enPawnDistance := getKingPawnDistance(enPawns&pawnColumnMasks[col], side, kingRow)
+
+		// pawn shield
+		switch {
+		case frPawnDistance == 1:
+			score += KING_SHIELD_PAWN_CLOSE_BONUS
+		case frPawnDistance == 2:
+			score += KING_SHIELD_PAWN_FAR_BONUS
+		case frPawnDistance == 0:
+			score += KING_SHIELD_MISSING_PENALTY
+			if enPawnDistance == 0 {
+				score += KING_OPEN_COLUMN_PENALTY
+			}
+		}
+
+		// pawn storm
+		switch enPawnDistance {
+		case 2:
+			score += KING_PAWN_STORM_2_PENALTY
+		case 3:
+			score += KING_PAWN_STORM_3_PENALTY
+		}
+	}
+
+	return score
+}
+
+// returns the distance in rows between the king row and the closest of the pawns in front of the king (zero if there are no pawns)
+func getKingPawnDistance(pawnsInFront Bitboard, side int, kingRow int) int {
+	if pawnsInFront == 0 {
+		return 0
+	}
+
+	// the closest pawn is the lowest square for white, and the highest square for black
+	closestSq := pawnsInFront.getMSBSq()
+	if side == SIDE_BLACK {
+		closestSq = pawnsInFront.getLSBSq()
+	}
+
+	closestRow, _ := rowAndColFromSq(closestSq)
+	if closestRow > kingRow {
+		return closestRow - kingRow
+	}
+	return kingRow - closestRow
+}
@@ -0,0 +1,178 @@
+package main
+
+// --------------------------------------------------------------------------------------------------------------------
+// --------------------------------------------------- Passed Pawns ---------------------------------------------------
+// --------------------------------------------------------------------------------------------------------------------
+/*
+A passed pawn has no enemy pawns in front of it in the left, middle and right columns, so only pieces can stop it.
+The further a passed pawn has advanced, the more dangerous it is.
+
+The evaluation of passed pawns is split in two parts:
+
+Pawn structure part (stored in the pawn hash table, because it only depends on the pawns):
+- A bonus for each passed pawn that grows with the row of the pawn (from the side of the pawn).
+- If friendly pawns are doubled on a passed column, only the front pawn is counted as passed.
+- The passed pawns of both sides are also stored in the pawn hash table, so that we don't need to find them again.
+
+Piece and king part (calculated for each position, because it depends on the other pieces):
+- Blocked: a passed pawn with any piece directly in front of it loses part of its row bonus.
+- Rook behind: a bonus for a friendly rook behind the passed pawn on the same column (with no pieces in between),
+  because the rook supports the pawn all the way to promotion.
+- King distance: in the endgame, a bonus for the enemy king being far from the promotion square,
+  and a penalty for the friendly king being far from the promotion square. This grows with the row of the pawn.
+- Rule of the square: in pawn endgames (the enemy side only has a king and pawns), a passed pawn with a clear path
+  that the enemy king can't catch anymore will promote, and gets a large bonus.
+*/
+
+const (
+	PASSED_PAWN_BLOCKED_DIVISOR int = 2 // a blocked passed pawn loses its row bonus divided by this
+	PASSED_PAWN_ROOK_BEHIND     int = 20
+
+	PASSED_PAWN_ENEMY_KING_DISTANCE    int = 4  // bonus for each square of distance between the enemy king and the promotion square
+	PASSED_PAWN_FRIENDLY_KING_DISTANCE int = -2 // penalty for each square of distance between the friendly king and the promotion square
+
+	PASSED_PAWN_UNSTOPPABLE int = 600 // bonus for a passed pawn in a pawn endgame that the enemy king can't catch
+)
+
+// bonus for a passed pawn on each row (from the side of the pawn, pawns are never on the 1st or 8th row)
+var evalPassedPawnRowBonus [8]int = [8]int{0, 10, 15, 25, 40, 65, 100, 0}
+
+// returns the passed pawn row bonus (or penalty for black) from the white side, and whether the pawn is passed
+// the front pawn of friendly doubled pawns is the only one counted as passed
+func getPassedPawnStructureScore(side int, pawnSq int, frPawns Bitboard, enPawns Bitboard) (int, bool) {
+
+	_, pawnCol := rowAndColFromSq(pawnSq)
+	enemyPawnsInFront := enPawns & pawnPassedMasks[side][pawnSq]
+	friendlyPawnsInFront := frPawns & pawnPassedMasks[side][pawnSq] & pawnColumnMasks[pawnCol]
+	if enemyPawnsInFront != 0 || friendlyPawnsInFront != 0 {
+		return 0, false
+	}
+
+	if side == SIDE_BLACK {
+		return 0 - evalPassedPawnRowBonus[getRelativeRow(side, pawnSq)], true
+	}
+	return evalPassedPawnRowBonus[getRelativeRow(side, pawnSq)], true
+}
+
+// returns the score of the passed pawns from the white side for the terms that depend on the pieces and kings
+func (pos *Position) evalPassedPawns(whitePassed Bitboard, blackPassed Bitboard) int {
+
+	// the king distance is only relevant in the endgame
+	evalStage := pos.evalMidVsEndStage
+	if evalStage > STAGE_VAL_STARTING { // cap to the max stage value
+		evalStage = STAGE_VAL_STARTING
+	}
+
+	whiteScore, whiteKingScore := pos.getPassedPawnsScore(SIDE_WHITE, whitePassed)
+	blackScore, blackKingScore := pos.getPassedPawnsScore(SIDE_BLACK, blackPassed)
+
+	kingScore := ((whiteKingScore - blackKingScore) * (STAGE_VAL_STARTING - evalStage)) / STAGE_VAL_STARTING
+	return whiteScore - blackScore + kingScore
+}
+
+// returns the score for the passed pawns of the side, and separately the king distance score (not tapered yet)
+func (pos *Position) getPassedPawnsScore(side int, passedPawns Bitboard) (int, int) {
+
+	if passedPawns == 0 {
+		return 0, 0
+	}
+
+	enSide := SIDE_BLACK
+	promotionRow := 7
+	if side == SIDE_BLACK {
+		enSide = SIDE_WHITE
+		promotionRow = 0
+	}
+
+	allPieces := pos.piecesAll[SIDE_BOTH]
+	frRooks := pos.pieces[side][PIECE_ROOK]
+	frKingSq := pos.pieces[side][PIECE_KING].getLSBSq()
+	enKingSq := pos.pieces[enSide][PIECE_KING].getLSBSq()
+
+	// a pawn endgame for the enemy side (only the king and pawns left)
+	enPieces := pos.piecesAll[enSide] & ^pos.pieces[enSide][PIECE_KING] & ^pos.pieces[enSide][PIECE_PAWN]
+	isEnPawnEndgame := enPieces == 0
+
+	score := 0
+	kingScore := 0
+	for passedPawns != 0 {
+		pawnSq := passedPawns.popBitGetSq()
+		_, pawnCol := rowAndColFromSq(pawnSq)
+		relativeRow := getRelativeRow(side, pawnSq)
+		promotionSq := sqFromRowAndCol(promotionRow, pawnCol)
+
+		// the squares in front of and behind the pawn on the same column
+		pathInFront := pawnPassedMasks[side][pawnSq] & pawnColumnMasks[pawnCol]
+		pathBehind := pawnPassedMasks[enSide][pawnSq] & pawnColumnMasks[pawnCol]
+
+		// blocked by any piece directly in front
+		sqInFront := pawnSq + 8
+		if side == SIDE_BLACK {
+			sqInFront = pawnSq - 8
+		}
+		if allPieces.isBitSet(sqInFront) {
+			score -= evalPassedPawnRowBonus[relativeRow] / PASSED_PAWN_BLOCKED_DIVISOR
+		}
+
+		// friendly rook behind the pawn (the rook must see the pawn)
+		if getRookMovesPseudo(pawnSq, allPieces)&pathBehind&frRooks != 0 {
+			score += PASSED_PAWN_ROOK_BEHIND
+		}
+
+		// king distance to the promotion square
+		enKingDistance := getSqDistance(enKingSq, promotionSq)
+		frKingDistance := getSqDistance(frKingSq, promotionSq)
+		kingScore += (enKingDistance*PASSED_PAWN_ENEMY_KING_DISTANCE + frKingDistance*PASSED_PAWN_FRIENDLY_KING_DISTANCE) * relativeRow
+
+		// rule of the square (only with a clear path to promotion)
+		if isEnPawnEndgame && pathInFront&allPieces == 0 {
+
+			// the number of moves the pawn needs to promote (a pawn on the starting row can move 2 squares)
+			pawnMoves := 7 - relativeRow
+			if relativeRow == 1 {
+				pawnMoves--
+			}
+
+			// the enemy king gets an extra move if it is the enemy turn
+			enKingMoves := enKingDistance
+			if (side == SIDE_WHITE) != pos.isWhiteTurn {
+				enKingMoves--
+			}
+
+			if enKingMoves > pawnMoves {
+				score += PASSED_PAWN_UNSTOPPABLE
+			}
+		}
+	}
+
+	return score, kingScore
+}
+
+// returns the row of the square from the side (the 1st row of the side is 0)
+func getRelativeRow(side int, sq int) int {
+	row, _ := rowAndColFromSq(sq)
+	if side == SIDE_BLACK {
+		return 7 - row
+	}
+	return row
+}
+
+// returns the number of king moves between 2 squares
+func getSqDistance(sqA int, sqB int) int {
+	rowA, colA := rowAndColFromSq(sqA)
+	rowB, colB := rowAndColFromSq(sqB)
+
+	rowDistance := rowA - rowB
+	if rowDistance < 0 {
+		rowDistance = 0 - rowDistance
+	}
+	colDistance := colA - colB
+	if colDistance < 0 {
+		colDistance = 0 - colDistance
+	}
+
+	if rowDistance > colDistance {
+		return rowDistance
+	}
+	return colDistance
+}
@@ -14,9 +14,11 @@ const (
 )
 
 type PawnStructureTable struct {
-	whitePawns Bitboard
-	blackPawns Bitboard
-	value      int
+	whitePawns  Bitboard
+	blackPawns  Bitboard
+	value       int
+	whitePassed Bitboard // the passed pawns of each side (see eval_passed_pawns.go)
+	blackPassed Bitboard
 }
 
 var pawnColumnMasks [8]Bitboard // masks where all the bits for that column only is set
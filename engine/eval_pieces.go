@@ -0,0 +1,211 @@
+package main
+
+// --------------------------------------------------------------------------------------------------------------------
+// --------------------------------------------------- Piece Evaluation -----------------------------------------------
+// --------------------------------------------------------------------------------------------------------------------
+/*
+Positional terms for specific piece types (on top of the heatmaps and mobility):
+- Bishop pair: a bonus for having 2 or more bishops, because together they cover squares of both colours.
+- Rooks on open columns: a bonus for a rook on a column without any pawns,
+  and a smaller bonus for a rook on a semi-open column (without friendly pawns, but with enemy pawns).
+- Rooks on the 7th row: a bonus for a rook on the 7th row (from the side of the rook),
+  but only when it traps the enemy king on the back row, or attacks enemy pawns that are still on their starting row.
+- Outposts: a bonus for a knight or bishop in the enemy half of the board (rows 4 to 6 from the side of the piece),
+  that is protected by a friendly pawn, and that can never be attacked by an enemy pawn
+  (there are no enemy pawns left on the columns next to the piece that are in front of it).
+- Connected rooks: a bonus when two rooks protect each other on the same row or column (no pieces between them).
+- Bad bishops: a penalty for each friendly pawn on the same square colour as the bishop,
+  because those pawns block the bishop and leave the squares of the other colour weak.
+
+Each term has a separate mid and end game value, which is blended for the current game stage in the same way as the heatmaps.
+*/
+
+const (
+	BISHOP_PAIR_BONUS_MID int = 25
+	BISHOP_PAIR_BONUS_END int = 50
+
+	ROOK_OPEN_COLUMN_BONUS_MID      int = 25
+	ROOK_OPEN_COLUMN_BONUS_END      int = 10
+	ROOK_SEMI_OPEN_COLUMN_BONUS_MID int = 12
+	ROOK_SEMI_OPEN_COLUMN_BONUS_END int = 5
+	ROOK_SEVENTH_ROW_BONUS_MID      int = 15
+	ROOK_SEVENTH_ROW_BONUS_END      int = 30
+	ROOKS_CONNECTED_BONUS_MID       int = 10
+	ROOKS_CONNECTED_BONUS_END       int = 5
+
+	KNIGHT_OUTPOST_BONUS_MID int = 25
+	KNIGHT_OUTPOST_BONUS_END int = 15
+	BISHOP_OUTPOST_BONUS_MID int = 12
+	BISHOP_OUTPOST_BONUS_END int = 6
+
+	BAD_BISHOP_PAWN_PENALTY_MID int = -3 // penalty for each friendly pawn on the same square colour as the bishop
+	BAD_BISHOP_PAWN_PENALTY_END int = -6
+)
+
+var evalLightSquaresMask Bitboard        // mask with all the light squares set (the dark squares are the inverse)
+var evalOutpostRowsMasks [2]Bitboard     // masks with the rows 4 to 6 (from the side of the piece) set
+var evalOutpostSafeMasks [2][64]Bitboard // masks with the columns next to the square set, for the rows in front of the square (for the specific side)
+
+// create the masks for the piece evaluation
+// note: this uses the pawn masks, so it must be done after the pawn masks are initialized
+func initEvalPiecesMasks() {
+
+	// light squares (a1 is a dark square)
+	evalLightSquaresMask = emptyBB
+	for sq := 0; sq < 64; sq++ {
+		row, col := rowAndColFromSq(sq)
+		if (row+col)%2 == 1 {
+			evalLightSquaresMask.setBit(sq)
+		}
+	}
+
+	// outpost rows
+	evalOutpostRowsMasks[SIDE_WHITE] = pawnRowMasks[3] | pawnRowMasks[4] | pawnRowMasks[5]
+	evalOutpostRowsMasks[SIDE_BLACK] = pawnRowMasks[2] | pawnRowMasks[3] | pawnRowMasks[4]
+
+	// the passed pawn masks without the column of the square itself
+	for side := 0; side < 2; side++ {
+		for sq := 0; sq < 64; sq++ {
+			_, col := rowAndColFromSq(sq)
+			evalOutpostSafeMasks[side][sq] = pawnPassedMasks[side][sq] & ^pawnColumnMasks[col]
+		}
+	}
+}
+
+// returns the piece evaluation score from the white side (blended with the game stage)
+func (pos *Position) evalPieces() int {
+
+	evalStage := pos.evalMidVsEndStage
+	if evalStage > STAGE_VAL_STARTING { // cap to the max stage value
+		evalStage = STAGE_VAL_STARTING
+	}
+
+	whiteMid, whiteEnd := pos.getPiecesScore(SIDE_WHITE)
+	blackMid, blackEnd := pos.getPiecesScore(SIDE_BLACK)
+
+	midValue := whiteMid - blackMid
+	endValue := whiteEnd - blackEnd
+	return ((midValue * evalStage) + (endValue * (STAGE_VAL_STARTING - evalStage))) / STAGE_VAL_STARTING
+}
+
+// returns the mid and end game scores of the piece terms for the side
+func (pos *Position) getPiecesScore(side int) (int, int) {
+
+	enSide := SIDE_BLACK
+	seventhRow := 6
+	backRow := 7
+	if side == SIDE_BLACK {
+		enSide = SIDE_WHITE
+		seventhRow = 1
+		backRow = 0
+	}
+
+	frPawns := pos.pieces[side][PIECE_PAWN]
+	enPawns := pos.pieces[enSide][PIECE_PAWN]
+	frBishops := pos.pieces[side][PIECE_BISHOP]
+	frRooks := pos.pieces[side][PIECE_ROOK]
+
+	scoreMid := 0
+	scoreEnd := 0
+
+	// ___ Bishops ___
+	// bishop pair
+	if frBishops.countBits() >= 2 {
+		scoreMid += BISHOP_PAIR_BONUS_MID
+		scoreEnd += BISHOP_PAIR_BONUS_END
+	}
+
+	bishopsPop := frBishops
+	for bishopsPop != 0 {
+		bishopSq := bishopsPop.popBitGetSq()
+
+		// outposts
+		if pos.isOutpost(side, bishopSq) {
+			scoreMid += BISHOP_OUTPOST_BONUS_MID
+			scoreEnd += BISHOP_OUTPOST_BONUS_END
+		}
+
+		// bad bishops (friendly pawns on the same square colour)
+		sameColourSquares := evalLightSquaresMask
+		if !evalLightSquaresMask.isBitSet(bishopSq) {
+			sameColourSquares = ^evalLightSquaresMask
+		}
+		blockingPawns := (frPawns & sameColourSquares).countBits()
+		scoreMid += blockingPawns * BAD_BISHOP_PAWN_PENALTY_MID
+		scoreEnd += blockingPawns * BAD_BISHOP_PAWN_PENALTY_END
+	}
+
+	// ___ Knights ___
+	knightsPop := pos.pieces[side][PIECE_KNIGHT]
+	for knightsPop != 0 {
+		knightSq := knightsPop.popBitGetSq()
+
+		// outposts
+		if pos.isOutpost(side, knightSq) {
+			scoreMid += KNIGHT_OUTPOST_BONUS_MID
+			scoreEnd += KNIGHT_OUTPOST_BONUS_END
+		}
+	}
+
+	// ___ Rooks ___
+	rooksPop := frRooks
+	for rooksPop != 0 {
+		rookSq := rooksPop.popBitGetSq()
+		rookRow, rookCol := rowAndColFromSq(rookSq)
+
+		// open and semi-open columns
+		if frPawns&pawnColumnMasks[rookCol] == 0 {
+			if enPawns&pawnColumnMasks[rookCol] == 0 {
+				scoreMid += ROOK_OPEN_COLUMN_BONUS_MID
+				scoreEnd += ROOK_OPEN_COLUMN_BONUS_END
+			} else {
+				scoreMid += ROOK_SEMI_OPEN_COLUMN_BONUS_MID
+				scoreEnd += ROOK_SEMI_OPEN_COLUMN_BONUS_END
+			}
+		}
+
+		// 7th row (only if the enemy king is on the back row, or there are enemy pawns on the 7th row)
+		if rookRow == seventhRow {
+			enKingOnBackRow := pos.pieces[enSide][PIECE_KING]&pawnRowMasks[backRow] != 0
+			enPawnsOnSeventhRow := enPawns&pawnRowMasks[seventhRow] != 0
+			if enKingOnBackRow || enPawnsOnSeventhRow {
+				scoreMid += ROOK_SEVENTH_ROW_BONUS_MID
+				scoreEnd += ROOK_SEVENTH_ROW_BONUS_END
+			}
+		}
+	}
+
+	// connected rooks (the rooks can see each other, only counted once)
+	if frRooks.countBits() >= 2 {
+		rooksCopy := frRooks
+		firstRookSq := rooksCopy.popBitGetSq()
+		if getRookMovesPseudo(firstRookSq, pos.piecesAll[SIDE_BOTH])&rooksCopy != 0 {
+			scoreMid += ROOKS_CONNECTED_BONUS_MID
+			scoreEnd += ROOKS_CONNECTED_BONUS_END
+		}
+	}
+
+	return scoreMid, scoreEnd
+}
+
+// returns true if the square is an outpost for a knight or bishop of the side
+// (in the enemy half, protected by a friendly pawn, and can't be attacked by enemy pawns)
+func (pos *Position) isOutpost(side int, sq int) bool {
+
+	if !evalOutpostRowsMasks[side].isBitSet(sq) {
+		return false
+	}
+
+	enSide := SIDE_BLACK
+	if side == SIDE_BLACK {
+		enSide = SIDE_WHITE
+	}
+
+	// protected by a friendly pawn
+	if pos.pieces[side][PIECE_PAWN]&movePawnsAttackingKingMasks[sq][side] == 0 {
+		return false
+	}
+
+	// no enemy pawns that can move forward to attack the square
+	return pos.pieces[enSide][PIECE_PAWN]&evalOutpostSafeMasks[side][sq] == 0
+}
@@ -0,0 +1,204 @@
+package main
+
+// --------------------------------------------------------------------------------------------------------------------
+// ------------------------------------------------------ Threats -----------------------------------------------------
+// --------------------------------------------------------------------------------------------------------------------
+/*
+Threats score pieces that are attacked in a way that will likely win material, before quiescence search finds it.
+
+At the start of the evaluation (evalPosAfter), we build the attack bitboards of each piece type for both sides once
+(the squares attacked by all the pawns, all the knights, and so on), and all the threat terms use them:
+- Attacked by pawns: a bonus for each enemy knight, bishop, rook and queen attacked by a friendly pawn.
+- Attacked by minors: a bonus for each enemy minor piece, and a larger bonus for each enemy rook and queen,
+  attacked by a friendly knight or bishop.
+- Hanging pieces: a bonus for each enemy knight, bishop, rook and queen that is attacked, but not defended.
+- Safe pawn pushes: a bonus for each enemy knight, bishop, rook and queen that a friendly pawn can attack with a push
+  to a safe square (not attacked by enemy pawns, and defended or not attacked by the enemy at all).
+  Pieces that are already attacked by friendly pawns are not counted again.
+
+The attacks ignore pins and checks, so they are not exact, but they are cheap and good enough for the evaluation.
+While building the attacks, we also count the attack units on the king zones for king safety (see eval_king_safety.go).
+*/
+
+const (
+	THREAT_BY_PAWN_BONUS        int = 60 // bonus for each enemy piece attacked by a friendly pawn
+	THREAT_BY_MINOR_ON_MINOR    int = 20 // bonus for each enemy minor piece attacked by a friendly minor piece
+	THREAT_BY_MINOR_ON_MAJOR    int = 40 // bonus for each enemy rook or queen attacked by a friendly minor piece
+	THREAT_HANGING_PIECE_BONUS  int = 35 // bonus for each enemy piece that is attacked and not defended
+	THREAT_SAFE_PAWN_PUSH_BONUS int = 20 // bonus for each enemy piece that can be attacked by a safe pawn push
+)
+
+const (
+	THREAT_ATTACKS_ALL   int = 6 // index of the attacks of all the pieces together (after the 6 piece types)
+	THREAT_ATTACKS_KINDS int = 7 // the 6 piece types, and all the pieces together
+)
+
+// the attacks of both sides, built once for each evaluation
+type EvalAttacks struct {
+	squares             [2][THREAT_ATTACKS_KINDS]Bitboard // squares attacked by each piece type of each side, and all the pieces of each side together
+	kingZoneAttackUnits [2]int                            // attack units of the pieces of each side on the enemy king zone (zero with too few attackers)
+}
+
+// returns the threats score from the white side
+func (pos *Position) evalThreats(attacks *EvalAttacks) int {
+	return pos.getThreatsScore(SIDE_WHITE, attacks) - pos.getThreatsScore(SIDE_BLACK, attacks)
+}
+
+// builds the attack bitboards for both sides, and counts the attack units on the king zones
+func (pos *Position) getEvalAttacks() EvalAttacks {
+
+	var attacks EvalAttacks
+	allPieces := pos.piecesAll[SIDE_BOTH]
+
+	for side := 0; side < 2; side++ {
+
+		enSide := SIDE_BLACK
+		if side == SIDE_BLACK {
+			enSide = SIDE_WHITE
+		}
+
+		// the board can be empty before a position is set up, so we check that the kings are there
+		enKingZone := emptyBB
+		if pos.pieces[enSide][PIECE_KING] != 0 {
+			enKingZone = evalKingZoneMasks[pos.pieces[enSide][PIECE_KING].getLSBSq()]
+		}
+		kingZoneAttackers := 0
+		kingZoneAttackUnits := 0
+
+		// kings
+		if pos.pieces[side][PIECE_KING] != 0 {
+			attacks.squares[side][PIECE_KING] = getKingMovesPseudo(pos.pieces[side][PIECE_KING].getLSBSq())
+		}
+
+		// queens
+		queens := pos.pieces[side][PIECE_QUEEN]
+		for queens != 0 {
+			queenAttacks := getQueenMovesPseudo(queens.popBitGetSq(), allPieces)
+			attacks.squares[side][PIECE_QUEEN] |= queenAttacks
+			if queenAttacks&enKingZone != 0 {
+				kingZoneAttackers++
+				kingZoneAttackUnits += (queenAttacks & enKingZone).countBits() * KING_ATTACK_WEIGHT_QUEEN
+			}
+		}
+
+		// rooks
+		rooks := pos.pieces[side][PIECE_ROOK]
+		for rooks != 0 {
+			rookAttacks := getRookMovesPseudo(rooks.popBitGetSq(), allPieces)
+			attacks.squares[side][PIECE_ROOK] |= rookAttacks
+			if rookAttacks&enKingZone != 0 {
+				kingZoneAttackers++
+				kingZoneAttackUnits += (rookAttacks & enKingZone).countBits() * KING_ATTACK_WEIGHT_ROOK
+			}
+		}
+
+		// knights
+		knights := pos.pieces[side][PIECE_KNIGHT]
+		for knights != 0 {
+			knightAttacks := getKnightMovesPseudo(knights.popBitGetSq())
+			attacks.squares[side][PIECE_KNIGHT] |= knightAttacks
+			if knightAttacks&enKingZone != 0 {
+				kingZoneAttackers++
+				kingZoneAttackUnits += (knightAttacks & enKingZone).countBits() * KING_ATTACK_WEIGHT_KNIGHT
+			}
+		}
+
+		// bishops
+		bishops := pos.pieces[side][PIECE_BISHOP]
+		for bishops != 0 {
+			bishopAttacks := getBishopMovesPseudo(bishops.popBitGetSq(), allPieces)
+			attacks.squares[side][PIECE_BISHOP] |= bishopAttacks
+			if bishopAttacks&enKingZone != 0 {
+				kingZoneAttackers++
+				kingZoneAttackUnits += (bishopAttacks & enKingZone).countBits() * KING_ATTACK_WEIGHT_BISHOP
+			}
+		}
+
+		// pawns
+		pawns := pos.pieces[side][PIECE_PAWN]
+		for pawns != 0 {
+			attacks.squares[side][PIECE_PAWN] |= moveAttackPawnsTable[pawns.popBitGetSq()][side]
+		}
+
+		// all pieces together
+		for pieceType := 0; pieceType < 6; pieceType++ {
+			attacks.squares[side][THREAT_ATTACKS_ALL] |= attacks.squares[side][pieceType]
+		}
+
+		// a single attacker is not counted (see eval_king_safety.go)
+		if kingZoneAttackers >= KING_ATTACK_MIN_ATTACKERS {
+			attacks.kingZoneAttackUnits[side] = kingZoneAttackUnits
+		}
+	}
+
+	return attacks
+}
+
+// returns the score of the threats made by the side on the enemy pieces
+func (pos *Position) getThreatsScore(side int, attacks *EvalAttacks) int {
+
+	enSide := SIDE_BLACK
+	pushDirection := 8
+	startingRow := 1
+	if side == SIDE_BLACK {
+		enSide = SIDE_WHITE
+		pushDirection = -8
+		startingRow = 6
+	}
+
+	enMinors := pos.pieces[enSide][PIECE_KNIGHT] | pos.pieces[enSide][PIECE_BISHOP]
+	enMajors := pos.pieces[enSide][PIECE_ROOK] | pos.pieces[enSide][PIECE_QUEEN]
+	enPieces := enMinors | enMajors
+
+	frAttacks := attacks.squares[side][THREAT_ATTACKS_ALL]
+	enAttacks := attacks.squares[enSide][THREAT_ATTACKS_ALL]
+	frMinorAttacks := attacks.squares[side][PIECE_KNIGHT] | attacks.squares[side][PIECE_BISHOP]
+
+	score := 0
+
+	// ___ Attacked by Pawns ___
+	attackedByPawns := enPieces & attacks.squares[side][PIECE_PAWN]
+	score += attackedByPawns.countBits() * THREAT_BY_PAWN_BONUS
+
+	// ___ Attacked by Minors ___
+	score += (enMinors & frMinorAttacks).countBits() * THREAT_BY_MINOR_ON_MINOR
+	score += (enMajors & frMinorAttacks).countBits() * THREAT_BY_MINOR_ON_MAJOR
+
+	// ___ Hanging Pieces ___
+	hangingPieces := enPieces & frAttacks & ^enAttacks
+	score += hangingPieces.countBits() * THREAT_HANGING_PIECE_BONUS
+
+	// ___ Safe Pawn Pushes ___
+	// safe squares are not attacked by enemy pawns, and are defended or not attacked by the enemy
+	allPieces := pos.piecesAll[SIDE_BOTH]
+	safeSquares := ^attacks.squares[enSide][PIECE_PAWN] & (frAttacks | ^enAttacks)
+	pushThreats := emptyBB
+
+	pawns := pos.pieces[side][PIECE_PAWN]
+	for pawns != 0 {
+		pawnSq := pawns.popBitGetSq()
+		pawnRow, _ := rowAndColFromSq(pawnSq)
+
+		// single push (pushes to the last row are promotions, and are scored by the search)
+		pushSq := pawnSq + pushDirection
+		pushRow, _ := rowAndColFromSq(pushSq)
+		if pushRow == 0 || pushRow == 7 || allPieces.isBitSet(pushSq) {
+			continue
+		}
+		if safeSquares.isBitSet(pushSq) {
+			pushThreats |= moveAttackPawnsTable[pushSq][side]
+		}
+
+		// double push from the starting row
+		if pawnRow == startingRow {
+			doublePushSq := pushSq + pushDirection
+			if !allPieces.isBitSet(doublePushSq) && safeSquares.isBitSet(doublePushSq) {
+				pushThreats |= moveAttackPawnsTable[doublePushSq][side]
+			}
+		}
+	}
+
+	score += (enPieces & pushThreats & ^attackedByPawns).countBits() * THREAT_SAFE_PAWN_PUSH_BONUS
+
+	return score
+}
@@ -7,16 +7,17 @@ package main
 Returns the state of the current position, which can be:
 - game ongoing
 - checkmate (white or black wins)
-- draw (stalemate, 3-fold repetition, or 50-move rule)
+- draw (stalemate, 3-fold repetition, 50-move rule, or insufficient material)
 */
 
 const (
-	STATE_ONGOING                int = 0
-	STATE_WIN_WHITE              int = 1
-	STATE_WIN_BLACK              int = 2
-	STATE_DRAW_STALEMATE         int = 3
-	STATE_DRAW_50_MOVE_RULE      int = 4
-	STATE_DRAW_3_FOLD_REPETITION int = 5
+	STATE_ONGOING                    int = 0
+	STATE_WIN_WHITE                  int = 1
+	STATE_WIN_BLACK                  int = 2
+	STATE_DRAW_STALEMATE             int = 3
+	STATE_DRAW_50_MOVE_RULE          int = 4
+	STATE_DRAW_3_FOLD_REPETITION     int = 5
+	STATE_DRAW_INSUFFICIENT_MATERIAL int = 6
 )
 
 // only call this after generating moves in a position
@@ -49,6 +50,12 @@ func (pos *Position) getGameStateAndStore() {
 		return
 	}
 
+	// insufficient material: neither side can checkmate anymore (see eval_endgames.go)
+	if pos.isInsufficientMaterial() {
+		pos.gameState = STATE_DRAW_INSUFFICIENT_MATERIAL
+		return
+	}
+
 	// 3-fold repetition
 	countOfOccurences := 1 // current pos is the 1st occurence
 	for _, previousHash := range pos.previousHashes[pos.hash3FoldRepStart:pos.previousHashesCounter] {
@@ -125,3 +125,8 @@ func getPromotionStringFromType(inputPromotion int) string {
 	}
 	return ""
 }
+
+// converts a move to the uci long algebraic notation, for example "e2e4" or "e7e8q"
+func getUCIMoveString(move Move) string {
+	return getStringFromSq(move.getFromSq()) + getStringFromSq(move.getToSq()) + getPromotionStringFromType(move.getPromotionType())
+}
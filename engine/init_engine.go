@@ -41,6 +41,8 @@ func initEngine() {
 		initEvalTables()
 		initEvalMaterialAndStageTables()
 		initEvalPawnMasks()
+		initEvalKingSafetyMasks()
+		initEvalPiecesMasks()
 
 		// terminal gui
 		initGameStateToText()
@@ -35,8 +35,11 @@ type SearchDepthLog struct {
 	orderThreatMoves            int // number of times threat moves were ordered
 	orderKiller1                int // number of times 1st killer moves were ordered
 	orderKiller2                int // number of times 2nd killer moves were ordered
-	orderIterativeDeepeningMove int // number of times the best iterative deepening moves were ordered
+	orderIterativeDeepeningMove int // number of times the pv moves of the previous iteration were ordered
 	orderOtherQuietMoves        int // number of times the other quit moves were ordered
+	orderCounterMove            int // number of times the counter move was found when ordering the other quiet moves
+	counterMoveCutoffs          int // beta cuts in the other quiet moves by the counter move
+	continuationHistoryCutoffs  int // beta cuts in the other quiet moves by a move with a positive continuation history score
 
 	// move generation details
 	generatedLegalMovesFull int // nodes where full legal moves were generated
@@ -50,13 +53,26 @@ type SearchDepthLog struct {
 	qsOtherNodes          int // number of QS nodes evaluated (excluding leaf nodes)
 	qsStandPatBetaCuts    int // number of beta cuts in quiescence using stand pat
 	qsStandPatAlphaRaises int // number of alpha raises in quiescence using stand pat
+	qsSEEPrunedMoves      int // number of captures in quiescence not searched because they lose material according to SEE
+	qsInCheckNodes        int // number of QS nodes in check (no stand pat, all evasions are searched)
+	qsEvasionsTried       int // number of quiet evasions searched in quiescence
+	qsQuietChecksTried    int // number of quiet checks searched at the first ply of quiescence
 
 	// special search extensions and cuts
 	checkExtensions         int // nodes where the depth was extended due to a check
+	singularSearches        int // nodes where we did an exclusion search to test whether the hash move is singular
+	singularExtensions      int // exclusion searches where the hash move was singular (and extended)
+	singularMultiCuts       int // exclusion searches where another move also failed high, and the node was pruned (multi-cut)
 	nullMoveSuccesses       int // tried a null move and got a cutoff
 	nullMoveFailures        int // tried a null move and did not get a cutoff
+	probCutTries            int // nodes where we tried the good captures with ProbCut
+	probCutSuccesses        int // nodes where a good capture beat the ProbCut beta, and the node was pruned
 	staticNullMovePrunes    int // nodes where we had a static null move prune
 	staticNullMoveNonPrunes int // nodes where we did not have a static null move prune
+	deltaPrunedMoves        int // captures in qs not searched because they can't raise alpha (delta pruning)
+	futilityNodes           int // nodes where quiet moves were futile (frontier futility pruning)
+	futilityPrunedMoves     int // quiet moves not searched because of futility pruning
+	lateMovePrunedMoves     int // quiet moves not searched because of late move pruning
 
 	// details about cutoffs in each of the main move loops
 	loopedOverMoves int // number of nodes where we actually looped over moves
@@ -90,6 +106,13 @@ type SearchDepthLog struct {
 	lmrReducedNodes         int // other quiet nodes where lmr was applied
 	lmrReducedNodesFailures int // other quiet nodes where lmr was applied and it was a failure (re-searched)
 	lmrNonReducedNodes      int // other quiet nodes where lmr was not applied
+
+	// PVS and aspiration windows
+	pvsNullWindowSearches int // moves after the first move that were searched with a null window
+	pvsReSearches         int // null window searches that improved alpha and were re-searched with the full window
+	aspirationSearches    int // root searches with an aspiration window (including the re-searches)
+	aspirationFailLows    int // aspiration searches that failed low and were re-searched with a wider window
+	aspirationFailHighs   int // aspiration searches that failed high and were re-searched with a wider window
 }
 
 const (
@@ -100,8 +123,9 @@ const (
 type SearchLogger struct {
 
 	// final depth of the last search
-	depth   int // reached depth of the last search
-	qsDepth int // reached QS depth of the last search
+	depth    int // reached depth of the last search
+	qsDepth  int // reached QS depth of the last search
+	selDepth int // deepest ply from the root reached in the last search (including qs and extensions)
 
 	// overall time taken for the search
 	startTime    time.Time // starts when the search starts
@@ -383,6 +407,14 @@ func (log *SearchLogger) getMoveOrderingSummary() string {
 	orderOtherQuietPercent := getPercent(totalNodes, log.depthLogs[NODE_TYPE_NORMAL].orderOtherQuietMoves)
 	summary += "Ord OthQuiet: " + strconv.Itoa(orderOtherQuietPercent) + "%. "
 
+	// counter move: how often the counter move caused the cutoff when it was ordered
+	counterMoveCutPercent := getPercent(log.depthLogs[NODE_TYPE_NORMAL].orderCounterMove, log.depthLogs[NODE_TYPE_NORMAL].counterMoveCutoffs)
+	summary += "CntMv Cut: " + strconv.Itoa(counterMoveCutPercent) + "%. "
+
+	// continuation history: how many of the other quiet move cutoffs had a positive continuation history
+	contHistCutPercent := getPercent(log.depthLogs[NODE_TYPE_NORMAL].quietOtherMovesCutoffs, log.depthLogs[NODE_TYPE_NORMAL].continuationHistoryCutoffs)
+	summary += "ContHist Cut: " + strconv.Itoa(contHistCutPercent) + "%. "
+
 	// order iterative deepening move
 	orderIterDeepPercent := getPercent(totalNodes, log.depthLogs[NODE_TYPE_NORMAL].orderIterativeDeepeningMove+log.depthLogs[NODE_TYPE_QS].orderIterativeDeepeningMove)
 	summary += "Ord IterDeep: " + strconv.Itoa(orderIterDeepPercent) + "%. "
@@ -433,6 +465,16 @@ func (log *SearchLogger) getQsSummary() string {
 	summary += "Stand Pat (beta cuts: " + strconv.Itoa(qsBetaPercent) + "%, "
 	summary += "alpha raises: " + strconv.Itoa(qsAlphaPercent) + "%). "
 
+	// captures pruned by SEE
+	summary += "SEE pruned moves: " + strconv.Itoa(log.depthLogs[NODE_TYPE_QS].qsSEEPrunedMoves) + ". "
+
+	// in check nodes, evasions and quiet checks
+	inCheckPercent := getPercent(totalNodes, log.depthLogs[NODE_TYPE_QS].qsInCheckNodes)
+
+	summary += "In Check: " + strconv.Itoa(inCheckPercent) + "% ("
+	summary += "quiet evasions: " + strconv.Itoa(log.depthLogs[NODE_TYPE_QS].qsEvasionsTried) + "). "
+	summary += "Quiet checks: " + strconv.Itoa(log.depthLogs[NODE_TYPE_QS].qsQuietChecksTried) + ". "
+
 	return summary
 }
 
@@ -455,6 +497,15 @@ func (log *SearchLogger) getCheckExtensionsSummary() string {
 	summary += "normal: " + strconv.Itoa(normalCheckExtensionsPercent) + "%, "
 	summary += "qs: " + strconv.Itoa(qsCheckExtensionsPercent) + "%). "
 
+	// singular extensions
+	singularSearches := log.depthLogs[NODE_TYPE_NORMAL].singularSearches
+	singularExtensionsPercent := getPercent(singularSearches, log.depthLogs[NODE_TYPE_NORMAL].singularExtensions)
+	singularMultiCutsPercent := getPercent(singularSearches, log.depthLogs[NODE_TYPE_NORMAL].singularMultiCuts)
+
+	summary += "Singular Tests: " + strconv.Itoa(singularSearches) + " ("
+	summary += "extensions: " + strconv.Itoa(singularExtensionsPercent) + "%, "
+	summary += "multi-cuts: " + strconv.Itoa(singularMultiCutsPercent) + "%). "
+
 	return summary
 }
 
@@ -490,6 +541,14 @@ func (log *SearchLogger) getPruningAndReductionSummary() string {
 	summary += "success: " + strconv.Itoa(nullMoveSuccessPercent) + "%, "
 	summary += "failure: " + strconv.Itoa(nullMoveFailurePercent) + "%). "
 
+	// probcut
+	probCutTries := log.depthLogs[NODE_TYPE_NORMAL].probCutTries
+	probCutTryPercent := getPercent(totalNormalNodes, probCutTries)
+	probCutSuccessPercent := getPercent(probCutTries, log.depthLogs[NODE_TYPE_NORMAL].probCutSuccesses)
+
+	summary += "Try ProbCut: " + strconv.Itoa(probCutTryPercent) + "% ("
+	summary += "success: " + strconv.Itoa(probCutSuccessPercent) + "%). "
+
 	// lmr stats
 	lmrReduceNodes := log.depthLogs[NODE_TYPE_NORMAL].lmrReducedNodes
 	lmrNonReducedNodes := log.depthLogs[NODE_TYPE_NORMAL].lmrNonReducedNodes
@@ -514,6 +573,34 @@ func (log *SearchLogger) getPruningAndReductionSummary() string {
 	summary += "Try StNullMvPrune: " + strconv.Itoa(snmTryPercent) + "% ("
 	summary += "success rate: " + strconv.Itoa(snmTriesSuccessRate) + "%). "
 
+	// delta pruning (qs)
+	summary += "Delta pruned moves: " + strconv.Itoa(log.depthLogs[NODE_TYPE_QS].deltaPrunedMoves) + ". "
+
+	// frontier futility pruning
+	futilityNodesPercent := getPercent(totalNormalNodes, log.depthLogs[NODE_TYPE_NORMAL].futilityNodes)
+
+	summary += "Futility nodes: " + strconv.Itoa(futilityNodesPercent) + "% ("
+	summary += "pruned moves: " + strconv.Itoa(log.depthLogs[NODE_TYPE_NORMAL].futilityPrunedMoves) + "). "
+
+	// late move pruning (LMP)
+	summary += "LMP pruned moves: " + strconv.Itoa(log.depthLogs[NODE_TYPE_NORMAL].lateMovePrunedMoves) + ". "
+
+	// principal variation search (PVS)
+	pvsNullWindowSearches := log.depthLogs[NODE_TYPE_NORMAL].pvsNullWindowSearches
+	pvsReSearchPercent := getPercent(pvsNullWindowSearches, log.depthLogs[NODE_TYPE_NORMAL].pvsReSearches)
+
+	summary += "PVS null windows: " + strconv.Itoa(pvsNullWindowSearches) + " ("
+	summary += "re-searches: " + strconv.Itoa(pvsReSearchPercent) + "%). "
+
+	// aspiration windows
+	aspirationSearches := log.depthLogs[NODE_TYPE_NORMAL].aspirationSearches
+	aspirationFailLowPercent := getPercent(aspirationSearches, log.depthLogs[NODE_TYPE_NORMAL].aspirationFailLows)
+	aspirationFailHighPercent := getPercent(aspirationSearches, log.depthLogs[NODE_TYPE_NORMAL].aspirationFailHighs)
+
+	summary += "Aspiration windows: " + strconv.Itoa(aspirationSearches) + " ("
+	summary += "fail low: " + strconv.Itoa(aspirationFailLowPercent) + "%, "
+	summary += "fail high: " + strconv.Itoa(aspirationFailHighPercent) + "%). "
+
 	return summary
 }
 
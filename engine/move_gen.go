@@ -14,10 +14,42 @@ To generate legal moves of each piece, we generally follow these steps:
 1.2 Mask the moves with a king in check mask (if the king is in check, only certain squares can remove the check).
 1.3 Mask the moves with a pin mask (pieces that are pinned can only move along certain rays).
 The final result is only legal moves.
+
+Move generation modes:
+Quiescence search does not need all the quiet moves, so the mode decides which quiet moves are stored:
+- MOVE_GEN_MODE_ALL: all legal moves.
+- MOVE_GEN_MODE_EVASIONS: the threat moves, and the quiet moves only when in check.
+  When in check, all legal moves are evasions (the check mask already makes sure of that).
+  When not in check, there is nothing to evade, so no quiet moves are stored.
+- MOVE_GEN_MODE_CHECKS: the threat moves, and only the quiet moves that give check (including castling that gives check).
+  When in check, all evasions are stored.
+Threat moves (captures and promotions) are always stored in all modes.
+
+For the checks mode, before generating the moves we find the check squares of each piece type
+(the squares from which the piece would attack the enemy king), and the discovered check pieces
+(friendly pieces that block a friendly rook, bishop or queen from the enemy king).
+A quiet move gives check if it moves to a check square of the piece, or if a discovered check piece moves off its line to the enemy king.
+Castling gives check if the rook (or a piece uncovered by the king) attacks the enemy king after castling.
+See QuietMovesFilter below.
+
+The modes only decide which quiet moves are stored: every legal move is still generated and counted,
+because the mobility evaluation needs all the moves, and the total moves counter must count all legal moves
+so that checkmate and stalemate are detected correctly.
 */
 
+const (
+	MOVE_GEN_MODE_ALL      int = 0 // all legal moves
+	MOVE_GEN_MODE_EVASIONS int = 1 // threat moves, and the quiet moves only when in check (evasions)
+	MOVE_GEN_MODE_CHECKS   int = 2 // threat moves, and the quiet moves that give check (all evasions when in check)
+)
+
 // generate all the legal moves for a position
 func (pos *Position) generateLegalMoves() {
+	pos.generateLegalMovesForMode(MOVE_GEN_MODE_ALL)
+}
+
+// generate the legal moves for a position, only keeping the quiet moves needed for the move generation mode
+func (pos *Position) generateLegalMovesForMode(mode int) {
 
 	pos.logTime.allLogTypes[LOG_MOVE_GEN_TOTAL].start()
 
@@ -68,6 +100,10 @@ func (pos *Position) generateLegalMoves() {
 		kingSq, pos.piecesAll[SIDE_BOTH], pos.piecesAll[enSide], pos.piecesAll[frSide], pos.pieces[enSide][PIECE_QUEEN], pos.pieces[enSide][PIECE_ROOK],
 		pos.pieces[enSide][PIECE_KNIGHT], pos.pieces[enSide][PIECE_BISHOP], pos.pieces[enSide][PIECE_PAWN], pos.isWhiteTurn)
 
+	// ------------------------------------------------- Quiet Moves Filter ---------------------------------------------
+	// set up which quiet moves are stored for the move generation mode
+	quietFilter := pos.getQuietMovesFilter(mode, piecesAttKingBB != 0, frSide, enSide)
+
 	// ------------------------------------------------- King Moves ---------------------------------------------
 	// get king pseudo-legal moves
 	// filter out attacked squares
@@ -79,6 +115,9 @@ func (pos *Position) generateLegalMoves() {
 	// mask out moves to friendly pieces
 	kingMovesPseudo &= ^pos.piecesAll[frSide]
 
+	// get the quiet moves of the king to store for the mode
+	quietTargets := quietFilter.getQuietTargets(PIECE_KING, kingSq)
+
 	// check the remaining moves for legality
 	for kingMovesPseudo != 0 {
 
@@ -94,10 +133,12 @@ func (pos *Position) generateLegalMoves() {
 				pos.threatMoves[pos.threatMovesCounter] = getEncodedMove(kingSq, nextMoveSq, PIECE_KING, MOVE_TYPE_CAPTURE, PROMOTION_NONE)
 				pos.totalMovesCounter += 1
 				pos.threatMovesCounter += 1
-			} else { // quiet move
-				pos.quietMoves[pos.quietMovesCounter] = getEncodedMove(kingSq, nextMoveSq, PIECE_KING, MOVE_TYPE_QUIET, PROMOTION_NONE)
+			} else { // quiet move (only stored if needed for the mode)
+				if quietTargets.isBitSet(nextMoveSq) {
+					pos.quietMoves[pos.quietMovesCounter] = getEncodedMove(kingSq, nextMoveSq, PIECE_KING, MOVE_TYPE_QUIET, PROMOTION_NONE)
+					pos.quietMovesCounter += 1
+				}
 				pos.totalMovesCounter += 1
-				pos.quietMovesCounter += 1
 			}
 		}
 	}
@@ -164,17 +205,20 @@ func (pos *Position) generateLegalMoves() {
 			}
 		}
 
-		// finally save the remaining moves
+		// finally save the remaining moves (the quiet moves only if needed for the mode)
+		quietTargets = quietFilter.getQuietTargets(PIECE_QUEEN, nextQueenOriginSq)
 		for nextQueenMoves != 0 {
 			nextQueenTargetSq := nextQueenMoves.popBitGetSq()
 			if pos.piecesAll[enSide]&bbReferenceArray[nextQueenTargetSq] != 0 { // capture
 				pos.threatMoves[pos.threatMovesCounter] = getEncodedMove(nextQueenOriginSq, nextQueenTargetSq, PIECE_QUEEN, MOVE_TYPE_CAPTURE, PROMOTION_NONE)
 				pos.totalMovesCounter += 1
 				pos.threatMovesCounter += 1
-			} else { // quiet move
-				pos.quietMoves[pos.quietMovesCounter] = getEncodedMove(nextQueenOriginSq, nextQueenTargetSq, PIECE_QUEEN, MOVE_TYPE_QUIET, PROMOTION_NONE)
+			} else { // quiet move (only stored if needed for the mode)
+				if quietTargets.isBitSet(nextQueenTargetSq) {
+					pos.quietMoves[pos.quietMovesCounter] = getEncodedMove(nextQueenOriginSq, nextQueenTargetSq, PIECE_QUEEN, MOVE_TYPE_QUIET, PROMOTION_NONE)
+					pos.quietMovesCounter += 1
+				}
 				pos.totalMovesCounter += 1
-				pos.quietMovesCounter += 1
 			}
 		}
 	}
@@ -210,7 +254,8 @@ func (pos *Position) generateLegalMoves() {
 			}
 		}
 
-		// finally save the remaining moves
+		// finally save the remaining moves (the quiet moves only if needed for the mode)
+		quietTargets = quietFilter.getQuietTargets(PIECE_ROOK, nextRookOriginSq)
 		for nextRookMoves != 0 {
 			nextRookTargetSq := nextRookMoves.popBitGetSq()
 			if pos.piecesAll[enSide]&bbReferenceArray[nextRookTargetSq] != 0 { // capture
@@ -218,10 +263,12 @@ func (pos *Position) generateLegalMoves() {
 				pos.totalMovesCounter += 1
 				pos.threatMovesCounter += 1
 				mobilityBonusCounter++
-			} else { // quiet move
-				pos.quietMoves[pos.quietMovesCounter] = getEncodedMove(nextRookOriginSq, nextRookTargetSq, PIECE_ROOK, MOVE_TYPE_QUIET, PROMOTION_NONE)
+			} else { // quiet move (only stored if needed for the mode)
+				if quietTargets.isBitSet(nextRookTargetSq) {
+					pos.quietMoves[pos.quietMovesCounter] = getEncodedMove(nextRookOriginSq, nextRookTargetSq, PIECE_ROOK, MOVE_TYPE_QUIET, PROMOTION_NONE)
+					pos.quietMovesCounter += 1
+				}
 				pos.totalMovesCounter += 1
-				pos.quietMovesCounter += 1
 				mobilityBonusCounter++
 			}
 		}
@@ -258,7 +305,8 @@ func (pos *Position) generateLegalMoves() {
 			}
 		}
 
-		// finally save the remaining moves
+		// finally save the remaining moves (the quiet moves only if needed for the mode)
+		quietTargets = quietFilter.getQuietTargets(PIECE_BISHOP, nextBishopOriginSq)
 		for nextBishopMoves != 0 {
 			nextBishopTargetSq := nextBishopMoves.popBitGetSq()
 			if pos.piecesAll[enSide]&bbReferenceArray[nextBishopTargetSq] != 0 { // capture
@@ -266,10 +314,12 @@ func (pos *Position) generateLegalMoves() {
 				pos.totalMovesCounter += 1
 				pos.threatMovesCounter += 1
 				mobilityBonusCounter++
-			} else { // quiet move
-				pos.quietMoves[pos.quietMovesCounter] = getEncodedMove(nextBishopOriginSq, nextBishopTargetSq, PIECE_BISHOP, MOVE_TYPE_QUIET, PROMOTION_NONE)
+			} else { // quiet move (only stored if needed for the mode)
+				if quietTargets.isBitSet(nextBishopTargetSq) {
+					pos.quietMoves[pos.quietMovesCounter] = getEncodedMove(nextBishopOriginSq, nextBishopTargetSq, PIECE_BISHOP, MOVE_TYPE_QUIET, PROMOTION_NONE)
+					pos.quietMovesCounter += 1
+				}
 				pos.totalMovesCounter += 1
-				pos.quietMovesCounter += 1
 				mobilityBonusCounter++
 			}
 		}
@@ -306,7 +356,8 @@ func (pos *Position) generateLegalMoves() {
 			}
 		}
 
-		// finally save the remaining moves
+		// finally save the remaining moves (the quiet moves only if needed for the mode)
+		quietTargets = quietFilter.getQuietTargets(PIECE_KNIGHT, nextKnightOriginSq)
 		for nextKnightMoves != 0 {
 			nextKnightTargetSq := nextKnightMoves.popBitGetSq()
 			if pos.piecesAll[enSide]&bbReferenceArray[nextKnightTargetSq] != 0 { // capture
@@ -314,10 +365,12 @@ func (pos *Position) generateLegalMoves() {
 				pos.totalMovesCounter += 1
 				pos.threatMovesCounter += 1
 				mobilityBonusCounter++
-			} else { // quiet move
-				pos.quietMoves[pos.quietMovesCounter] = getEncodedMove(nextKnightOriginSq, nextKnightTargetSq, PIECE_KNIGHT, MOVE_TYPE_QUIET, PROMOTION_NONE)
+			} else { // quiet move (only stored if needed for the mode)
+				if quietTargets.isBitSet(nextKnightTargetSq) {
+					pos.quietMoves[pos.quietMovesCounter] = getEncodedMove(nextKnightOriginSq, nextKnightTargetSq, PIECE_KNIGHT, MOVE_TYPE_QUIET, PROMOTION_NONE)
+					pos.quietMovesCounter += 1
+				}
 				pos.totalMovesCounter += 1
-				pos.quietMovesCounter += 1
 				mobilityBonusCounter++
 			}
 		}
@@ -365,7 +418,8 @@ func (pos *Position) generateLegalMoves() {
 			}
 		}
 
-		// finally save the remaining moves
+		// finally save the remaining moves (the quiet moves only if needed for the mode)
+		quietTargets = quietFilter.getQuietTargets(PIECE_PAWN, nextPawnOriginSq)
 		for nextPawnMoves != 0 {
 			nextPawnTargetSq := nextPawnMoves.popBitGetSq()
 
@@ -413,10 +467,12 @@ func (pos *Position) generateLegalMoves() {
 					pos.totalMovesCounter += 1
 					pos.threatMovesCounter += 1
 
-				} else { // if there is not a promotion
-					pos.quietMoves[pos.quietMovesCounter] = getEncodedMove(nextPawnOriginSq, nextPawnTargetSq, PIECE_PAWN, MOVE_TYPE_QUIET, PROMOTION_NONE)
+				} else { // if there is not a promotion (only stored if needed for the mode)
+					if quietTargets.isBitSet(nextPawnTargetSq) {
+						pos.quietMoves[pos.quietMovesCounter] = getEncodedMove(nextPawnOriginSq, nextPawnTargetSq, PIECE_PAWN, MOVE_TYPE_QUIET, PROMOTION_NONE)
+						pos.quietMovesCounter += 1
+					}
 					pos.totalMovesCounter += 1
-					pos.quietMovesCounter += 1
 				}
 			}
 		}
@@ -546,9 +602,11 @@ func (pos *Position) generateLegalMoves() {
 						pos.pieces[enSide][PIECE_KNIGHT], pos.pieces[enSide][PIECE_BISHOP], pos.pieces[enSide][PIECE_PAWN], pos.pieces[enSide][PIECE_KING],
 						pos.isWhiteTurn) {
 
-						pos.quietMoves[pos.quietMovesCounter] = getEncodedMove(4, 6, PIECE_KING, MOVE_TYPE_CASTLE, PROMOTION_NONE)
+						if quietFilter.keepCastle(CASTLE_WHITE_KINGSIDE) {
+							pos.quietMoves[pos.quietMovesCounter] = getEncodedMove(4, 6, PIECE_KING, MOVE_TYPE_CASTLE, PROMOTION_NONE)
+							pos.quietMovesCounter += 1
+						}
 						pos.totalMovesCounter += 1
-						pos.quietMovesCounter += 1
 					}
 				}
 			}
@@ -574,9 +632,11 @@ func (pos *Position) generateLegalMoves() {
 						pos.pieces[enSide][PIECE_KNIGHT], pos.pieces[enSide][PIECE_BISHOP], pos.pieces[enSide][PIECE_PAWN], pos.pieces[enSide][PIECE_KING],
 						pos.isWhiteTurn) {
 
-						pos.quietMoves[pos.quietMovesCounter] = getEncodedMove(4, 2, PIECE_KING, MOVE_TYPE_CASTLE, PROMOTION_NONE)
+						if quietFilter.keepCastle(CASTLE_WHITE_QUEENSIDE) {
+							pos.quietMoves[pos.quietMovesCounter] = getEncodedMove(4, 2, PIECE_KING, MOVE_TYPE_CASTLE, PROMOTION_NONE)
+							pos.quietMovesCounter += 1
+						}
 						pos.totalMovesCounter += 1
-						pos.quietMovesCounter += 1
 					}
 				}
 			}
@@ -605,9 +665,11 @@ func (pos *Position) generateLegalMoves() {
 						pos.pieces[enSide][PIECE_KNIGHT], pos.pieces[enSide][PIECE_BISHOP], pos.pieces[enSide][PIECE_PAWN], pos.pieces[enSide][PIECE_KING],
 						pos.isWhiteTurn) {
 
-						pos.quietMoves[pos.quietMovesCounter] = getEncodedMove(60, 62, PIECE_KING, MOVE_TYPE_CASTLE, PROMOTION_NONE)
+						if quietFilter.keepCastle(CASTLE_BLACK_KINGSIDE) {
+							pos.quietMoves[pos.quietMovesCounter] = getEncodedMove(60, 62, PIECE_KING, MOVE_TYPE_CASTLE, PROMOTION_NONE)
+							pos.quietMovesCounter += 1
+						}
 						pos.totalMovesCounter += 1
-						pos.quietMovesCounter += 1
 					}
 				}
 			}
@@ -633,9 +695,11 @@ func (pos *Position) generateLegalMoves() {
 						pos.pieces[enSide][PIECE_KNIGHT], pos.pieces[enSide][PIECE_BISHOP], pos.pieces[enSide][PIECE_PAWN], pos.pieces[enSide][PIECE_KING],
 						pos.isWhiteTurn) {
 
-						pos.quietMoves[pos.quietMovesCounter] = getEncodedMove(60, 58, PIECE_KING, MOVE_TYPE_CASTLE, PROMOTION_NONE)
+						if quietFilter.keepCastle(CASTLE_BLACK_QUEENSIDE) {
+							pos.quietMoves[pos.quietMovesCounter] = getEncodedMove(60, 58, PIECE_KING, MOVE_TYPE_CASTLE, PROMOTION_NONE)
+							pos.quietMovesCounter += 1
+						}
 						pos.totalMovesCounter += 1
-						pos.quietMovesCounter += 1
 					}
 				}
 			}
@@ -656,6 +720,148 @@ func (pos *Position) generateLegalMoves() {
 	pos.logTime.allLogTypes[LOG_MOVE_GEN_TOTAL].stop()
 }
 
+// --------------------------------------------------------------------------------------------------------------------
+// ---------------------------------------------- Quiet Moves Filter --------------------------------------------------
+// --------------------------------------------------------------------------------------------------------------------
+// decides which quiet moves are stored for the move generation mode (see the modes at the top of the file)
+
+type QuietMovesFilter struct {
+	keepAll      bool        // all quiet moves are stored (all moves mode, or when in check)
+	checkSquares [6]Bitboard // for each piece type, the squares from which the piece attacks the enemy king
+	discoverers  Bitboard    // friendly pieces that give a discovered check when they move off their line to the enemy king
+	enKingSq     int         // square of the enemy king
+	castleChecks [4]bool     // for each castling move, whether it gives check
+}
+
+// returns the quiet moves filter for the mode
+func (pos *Position) getQuietMovesFilter(mode int, inCheck bool, frSide int, enSide int) QuietMovesFilter {
+
+	filter := QuietMovesFilter{}
+
+	// all quiet moves are evasions when in check
+	if mode == MOVE_GEN_MODE_ALL || inCheck {
+		filter.keepAll = true
+		return filter
+	}
+
+	// evasions when not in check (and checks without an enemy king on the board): no quiet moves
+	if mode == MOVE_GEN_MODE_EVASIONS || pos.pieces[enSide][PIECE_KING] == 0 {
+		return filter
+	}
+
+	// checks: direct check squares for each piece type (the king can't give a direct check)
+	occupied := pos.piecesAll[SIDE_BOTH]
+	filter.enKingSq = pos.pieces[enSide][PIECE_KING].getLSBSq()
+	filter.checkSquares[PIECE_KNIGHT] = getKnightMovesPseudo(filter.enKingSq)
+	filter.checkSquares[PIECE_BISHOP] = getBishopMovesPseudo(filter.enKingSq, occupied)
+	filter.checkSquares[PIECE_ROOK] = getRookMovesPseudo(filter.enKingSq, occupied)
+	filter.checkSquares[PIECE_QUEEN] = filter.checkSquares[PIECE_BISHOP] | filter.checkSquares[PIECE_ROOK]
+	filter.checkSquares[PIECE_PAWN] = movePawnsAttackingKingMasks[filter.enKingSq][frSide]
+
+	// checks: discovered check pieces
+	frRooksAndQueens := pos.pieces[frSide][PIECE_ROOK] | pos.pieces[frSide][PIECE_QUEEN]
+	frBishopsAndQueens := pos.pieces[frSide][PIECE_BISHOP] | pos.pieces[frSide][PIECE_QUEEN]
+	filter.discoverers = getDiscoveredCheckPieces(filter.enKingSq, occupied, pos.piecesAll[frSide], frRooksAndQueens, frBishopsAndQueens)
+
+	// checks: castling moves of the friendly side that give check
+	firstCastle, lastCastle := CASTLE_WHITE_KINGSIDE, CASTLE_WHITE_QUEENSIDE
+	if frSide == SIDE_BLACK {
+		firstCastle, lastCastle = CASTLE_BLACK_KINGSIDE, CASTLE_BLACK_QUEENSIDE
+	}
+	for castle := firstCastle; castle <= lastCastle; castle++ {
+		if pos.castlingRights[castle] {
+			filter.castleChecks[castle] = pos.isCastlingCheck(castle, frSide, filter.enKingSq)
+		}
+	}
+
+	return filter
+}
+
+// returns the target squares of the quiet moves to store for the piece on the origin square
+func (filter *QuietMovesFilter) getQuietTargets(pieceType int, originSq int) Bitboard {
+	if filter.keepAll {
+		return fullBB
+	}
+
+	// a discovered check piece gives check with all moves that leave its line to the enemy king
+	targets := filter.checkSquares[pieceType]
+	if filter.discoverers.isBitSet(originSq) {
+		targets |= ^getRayThroughSq(filter.enKingSq, originSq)
+	}
+	return targets
+}
+
+// returns true if the castling move should be stored for the mode
+func (filter *QuietMovesFilter) keepCastle(castle int) bool {
+	return filter.keepAll || filter.castleChecks[castle]
+}
+
+// returns true if castling gives check (by the rook, or by a piece uncovered by the king)
+func (pos *Position) isCastlingCheck(castle int, frSide int, enKingSq int) bool {
+
+	// the king and rook squares before and after castling
+	var kingFromSq, kingToSq, rookFromSq, rookToSq int
+	switch castle {
+	case CASTLE_WHITE_KINGSIDE:
+		kingFromSq, kingToSq, rookFromSq, rookToSq = 4, 6, 7, 5
+	case CASTLE_WHITE_QUEENSIDE:
+		kingFromSq, kingToSq, rookFromSq, rookToSq = 4, 2, 0, 3
+	case CASTLE_BLACK_KINGSIDE:
+		kingFromSq, kingToSq, rookFromSq, rookToSq = 60, 62, 63, 61
+	case CASTLE_BLACK_QUEENSIDE:
+		kingFromSq, kingToSq, rookFromSq, rookToSq = 60, 58, 56, 59
+	}
+
+	// the occupied squares and friendly rooks after castling
+	occupied := pos.piecesAll[SIDE_BOTH]
+	occupied.clearBit(kingFromSq)
+	occupied.clearBit(rookFromSq)
+	occupied.setBit(kingToSq)
+	occupied.setBit(rookToSq)
+	frRooks := pos.pieces[frSide][PIECE_ROOK]
+	frRooks.clearBit(rookFromSq)
+	frRooks.setBit(rookToSq)
+
+	// any friendly slider that attacks the enemy king after castling
+	frRooksAndQueens := frRooks | pos.pieces[frSide][PIECE_QUEEN]
+	frBishopsAndQueens := pos.pieces[frSide][PIECE_BISHOP] | pos.pieces[frSide][PIECE_QUEEN]
+	return getRookMovesPseudo(enKingSq, occupied)&frRooksAndQueens != 0 ||
+		getBishopMovesPseudo(enKingSq, occupied)&frBishopsAndQueens != 0
+}
+
+// returns the friendly pieces that are the only blocker between a friendly slider and the enemy king
+func getDiscoveredCheckPieces(enKingSq int, occupied Bitboard, frPieces Bitboard, frRooksAndQueens Bitboard, frBishopsAndQueens Bitboard) Bitboard {
+	discoverers := emptyBB
+
+	// the first friendly blockers on the rook rays, and then the bishop rays from the enemy king
+	rookBlockers := getRookMovesPseudo(enKingSq, occupied) & frPieces
+	for rookBlockers != 0 {
+		blockerSq := rookBlockers.popBitGetSq()
+		if getRookMovesPseudo(enKingSq, occupied&^bbReferenceArray[blockerSq])&frRooksAndQueens != 0 {
+			discoverers.setBit(blockerSq)
+		}
+	}
+	bishopBlockers := getBishopMovesPseudo(enKingSq, occupied) & frPieces
+	for bishopBlockers != 0 {
+		blockerSq := bishopBlockers.popBitGetSq()
+		if getBishopMovesPseudo(enKingSq, occupied&^bbReferenceArray[blockerSq])&frBishopsAndQueens != 0 {
+			discoverers.setBit(blockerSq)
+		}
+	}
+
+	return discoverers
+}
+
+// returns the full ray from the origin square that goes through the other square (empty if they are not on a line)
+func getRayThroughSq(originSq int, throughSq int) Bitboard {
+	for rayDirection := RAY_UP; rayDirection <= RAY_DL; rayDirection++ {
+		if moveRaysTable[originSq][rayDirection].isBitSet(throughSq) {
+			return moveRaysTable[originSq][rayDirection]
+		}
+	}
+	return emptyBB
+}
+
 // --------------------------------------------------------------------------------------------------------------------
 // ---------------- Generate Pseudo-Legal Moves (excluding castling, promoting, pinned pieces and checks) -------------
 // --------------------------------------------------------------------------------------------------------------------
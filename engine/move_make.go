@@ -24,6 +24,7 @@ func (pos *Position) makeMove(move Move) {
 	pos.previousGameStates[pos.previousGameStatesCounter].evalMidVsEndStage = pos.evalMidVsEndStage
 	pos.previousGameStates[pos.previousGameStatesCounter].evalWhiteMobility = pos.evalWhiteMobility
 	pos.previousGameStates[pos.previousGameStatesCounter].evalBlackMobility = pos.evalBlackMobility
+	pos.previousGameStates[pos.previousGameStatesCounter].move = move
 
 	pos.previousGameStatesCounter += 1
 
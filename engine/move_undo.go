@@ -21,6 +21,7 @@ type PreviousState struct {
 	evalMidVsEndStage int
 	evalWhiteMobility int
 	evalBlackMobility int
+	move              Move // the move played from this state (a blank move for null moves)
 }
 
 // --------------------------------------------------------------------------------------------------------------------
@@ -216,11 +216,13 @@ func (pos *Position) printBoardToTerminal() {
 
 // computer looks for and plays the best move in the position
 func (pos *Position) searchAndPlayBestMove(timePerMoveMs int) {
-	pos.searchForBestMove(timePerMoveMs)
+	// terminal searches are not stopped from outside, so clear any previous stop requests
+	pos.searchControl.reset()
+	pos.searchForBestMove(getNewSearchLimits(timePerMoveMs))
 	pos.makeMove(pos.bestMove)
 }
 
-var gameStateToText [6]string
+var gameStateToText [7]string
 
 func initGameStateToText() {
 	gameStateToText[STATE_ONGOING] = "Game is ongoing"
@@ -229,6 +231,7 @@ func initGameStateToText() {
 	gameStateToText[STATE_DRAW_STALEMATE] = "Draw by stalemate"
 	gameStateToText[STATE_DRAW_3_FOLD_REPETITION] = "Draw by 3-fold repetition"
 	gameStateToText[STATE_DRAW_50_MOVE_RULE] = "Draw by 50-move rule"
+	gameStateToText[STATE_DRAW_INSUFFICIENT_MATERIAL] = "Draw by insufficient material"
 }
 
 // start the terminal GUI loop until the game is over
@@ -164,6 +164,16 @@ func (pos *Position) startUCIInputLoop() {
 	// set up the input reader
 	inputReader := bufio.NewReader(os.Stdin)
 
+	// set up the option registry with the default settings
+	initUCIOptions()
+
+	// set up the search control
+	// searches run in the background, so that we can keep on reading commands (such as "stop") while the engine is thinking
+	pos.searchControl = getNewSearchControl()
+
+	// searches started from the GUI send "info" lines while searching
+	pos.searchPrintInfo = true
+
 	// set up the error log buffer
 	errorLogPositionBuffer := ""
 
@@ -172,8 +182,12 @@ func (pos *Position) startUCIInputLoop() {
 	for runCommandLoop {
 
 		// get the next input command to the engine
-		command, _ := inputReader.ReadString('\n')
+		// if the input was closed by the GUI, we treat it as a "quit" command
+		command, err := inputReader.ReadString('\n')
 		command = strings.TrimSpace(command)
+		if err != nil && command == "" {
+			command = "quit"
+		}
 
 		// respond to the command
 		// --------------------------------- UCI GAME COMMANDS -----------------------------------
@@ -187,7 +201,7 @@ func (pos *Position) startUCIInputLoop() {
 			pos.command_isReady()
 
 		} else if strings.HasPrefix(command, "setoption") {
-			pos.command_setOption()
+			pos.command_setOption(command)
 
 		} else if strings.HasPrefix(command, "register") {
 			pos.command_register()
@@ -200,34 +214,7 @@ func (pos *Position) startUCIInputLoop() {
 			pos.command_position(command)
 
 		} else if strings.HasPrefix(command, "go") {
-			response, success := pos.command_go(command)
-
-			// print error logs if the response is invalid
-			if !success {
-
-				// open the file in append mode; if the file doesn't exist, it will be created
-				file, err := os.OpenFile("error_logs.txt", os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
-				if err != nil {
-					log.Fatal(err)
-				}
-				defer file.Close()
-
-				// create the error log string to be added
-				errorLogStartTimeBuffer := time.Now().String()
-				errorLogGoBuffer := command
-				errorLogResponseBuffer := response
-
-				errorString := errorLogStartTimeBuffer + ": ERROR IN SEARCH. SELECTED A RANDOM MOVE. PREVIOUS COMMANDS RECEIVED:\n" + errorLogPositionBuffer + "\n" + errorLogGoBuffer + "\n" + errorLogResponseBuffer + "\n"
-
-				// now add the log strings to the error log file
-				_, err = fmt.Fprintln(file, errorString)
-				if err != nil {
-					log.Fatal(err)
-				}
-			}
-
-			// send the best move response
-			fmt.Printf("%v\n", response)
+			pos.startGoCommandInBackground(command, errorLogPositionBuffer)
 
 		} else if strings.HasPrefix(command, "stop") {
 			pos.command_stop()
@@ -241,12 +228,79 @@ func (pos *Position) startUCIInputLoop() {
 
 			// --------------------------------- TERMINAL GAME COMMANDS -----------------------------------
 		} else if strings.HasPrefix(command, "terminalnewgame") {
+			pos.searchControl.stopUntimedSearchAndWait()
 			initEngine()
 			pos.reset()
+			pos.clearSearchTables()
 			pos.initPositionFromFen(startingFen)
+			pos.searchPrintInfo = false // the terminal prints its own search summary
 			pos.startGameLoopTerminalGUI()
+			pos.searchPrintInfo = true
+		}
+
+	}
+}
+
+// starts the search for a "go" command on its own goroutine, and sends the best move once the search is done
+// the input loop keeps on running in the meantime, so that commands such as "stop" and "isready" can be processed
+func (pos *Position) startGoCommandInBackground(command string, positionCommand string) {
+
+	// a new search can only start once the previous search is done
+	pos.searchControl.stopUntimedSearchAndWait()
+
+	// reset the stop flag before the search starts (and not inside the goroutine),
+	// so that a "stop" command received directly after "go" is not lost
+	pos.searchControl.reset()
+
+	// the same applies to searches that only end on "stop", so that a following command can stop them
+	if isUntimedGoCommand(command) {
+		pos.searchControl.setUntimed()
+	}
+
+	pos.searchControl.startInBackground(func() {
+		response, success := pos.command_go(command)
+
+		// print error logs if the response is invalid
+		if !success {
+			writeSearchErrorLog(positionCommand, command, response)
+		}
+
+		// send the best move response
+		fmt.Printf("%v\n", response)
+	})
+}
+
+// returns true if the "go" command starts a search that only ends on "stop" (infinite, or ponder before "ponderhit")
+func isUntimedGoCommand(command string) bool {
+	for _, part := range strings.Fields(command) {
+		if part == "infinite" || part == "ponder" {
+			return true
 		}
+	}
+	return false
+}
 
+// writes the commands that led to an invalid search response to the error log file
+func writeSearchErrorLog(positionCommand string, goCommand string, response string) {
+
+	// open the file in append mode; if the file doesn't exist, it will be created
+	file, err := os.OpenFile("error_logs.txt", os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer file.Close()
+
+	// create the error log string to be added
+	errorLogStartTimeBuffer := time.Now().String()
+	errorLogGoBuffer := goCommand
+	errorLogResponseBuffer := response
+
+	errorString := errorLogStartTimeBuffer + ": ERROR IN SEARCH. SELECTED A RANDOM MOVE. PREVIOUS COMMANDS RECEIVED:\n" + positionCommand + "\n" + errorLogGoBuffer + "\n" + errorLogResponseBuffer + "\n"
+
+	// now add the log strings to the error log file
+	_, err = fmt.Fprintln(file, errorString)
+	if err != nil {
+		log.Fatal(err)
 	}
 }
 
@@ -427,7 +481,8 @@ func (pos *Position) command_uci() {
 	fmt.Printf("id author Johan van Deventer\n")
 
 	// <<< 2 >>> Options
-	// none for now
+	// send all the options from the option registry
+	printUCIOptions()
 
 	// <<< 2 >>> Final response
 	fmt.Printf("uciok\n")
@@ -495,8 +550,18 @@ GUI to engine:
     "setoption name Clear Hash\n"
     "setoption name NalimovPath value c:\chess\tb\4;c:\chess\tb\5\n"
 */
-func (pos *Position) command_setOption() {
-	// no options for now
+func (pos *Position) command_setOption(command string) {
+
+	// options are only changed while the engine is waiting
+	// the GUI should not send options during a search, so we ignore them instead of blocking the input loop
+	if pos.searchControl.isSearchRunning() {
+		fmt.Printf("info string option ignored while searching: %v\n", command)
+		return
+	}
+
+	// get the name and value from the command, and update the option in the option registry
+	name, value := parseSetOptionCommand(command)
+	pos.setUCIOption(name, value)
 }
 
 // --------------------------------------------------------- Register -----------------------------------------------
@@ -557,9 +622,16 @@ GUI to engine:
     after "ucinewgame" to wait for the engine to finish its operation.
 */
 func (pos *Position) command_uciNewGame() {
+
+	// the position can only change once a running search is done (a search that only ends on "stop" is stopped first)
+	pos.searchControl.stopUntimedSearchAndWait()
+
 	initEngine()
 	pos.reset()
 
+	// the tables learned in the previous game are not relevant anymore
+	pos.clearSearchTables()
+
 	// "isready" will be sent by the GUI after this command
 	// so once the init is done that command will be processed correctly
 	// nothing else is needed here
@@ -578,6 +650,10 @@ GUI to engine:
     the last position sent to the engine, the GUI should have sent a "ucinewgame" inbetween.
 */
 func (pos *Position) command_position(command string) {
+
+	// the position can only change once a running search is done (a search that only ends on "stop" is stopped first)
+	pos.searchControl.stopUntimedSearchAndWait()
+
 	pos.reset()
 
 	parts := strings.Split(command, " ")
@@ -603,9 +679,18 @@ func (pos *Position) command_position(command string) {
 	}
 }
 
-// translates the uci move input to a move recognized by the engine
+// translates the uci move input to a move recognized by the engine and plays it
 // it will do nothing if a move is not recognized
 func (pos *Position) makeUCIMove(input string) {
+	move := pos.getUCIMove(input)
+	if move != BLANK_MOVE {
+		pos.makeMove(move)
+	}
+}
+
+// translates the uci move input to the matching legal move in the position
+// it returns a blank move if the input is not a legal move
+func (pos *Position) getUCIMove(input string) Move {
 
 	// split the input to the separate string parts
 	var fromStr string
@@ -620,7 +705,7 @@ func (pos *Position) makeUCIMove(input string) {
 		toStr = input[2:4]
 		promoteStr = input[4:]
 	} else { // unrecognized move
-		return
+		return BLANK_MOVE
 	}
 
 	// convert the string parts to engine ints
@@ -646,7 +731,7 @@ func (pos *Position) makeUCIMove(input string) {
 
 	// if there are no available moves, return
 	if pos.totalMovesCounter <= 0 {
-		return
+		return BLANK_MOVE
 	}
 
 	// else copy the moves
@@ -655,19 +740,15 @@ func (pos *Position) makeUCIMove(input string) {
 	copy(allMoves[pos.threatMovesCounter:], pos.quietMoves[:pos.quietMovesCounter])
 
 	// loop over moves
-	// and where the input matches the move, play that move
-	var playedMove Move
-	foundMove := false
+	// and return the move where the input matches the move
+	foundMove := BLANK_MOVE
 	for _, move := range allMoves {
 		if move.getFromSq() == fromSq && move.getToSq() == toSq && move.getPromotionType() == promoteType {
-			playedMove = move
-			foundMove = true
+			foundMove = move
 		}
 	}
 
-	if foundMove {
-		pos.makeMove(playedMove)
-	}
+	return foundMove
 }
 
 // --------------------------------------------------------- Go -----------------------------------------------
@@ -728,93 +809,141 @@ Engine to GUI:
 func (pos *Position) command_go(command string) (string, bool) {
 
 	// split the command into a slice of strings
-	parts := strings.Split(command, " ")
+	parts := strings.Fields(command)
 
 	// reset the time management variables
-	allowedTimeMs := 0
-	hasIncrement := false
-	allocateTime := true
+	timeRemainingMs := 0
+	incrementMs := 0
+	movesToGo := 0
+	moveTimeMs := 0
+	hasTimeControl := false
+	isInfinite := false
+	isPonder := false
+
+	// start with limits that only depend on time, the other limits are set from the command below
+	limits := getNewSearchLimits(0)
+	hasOtherLimit := false
 
 	// loop over the command parts
 	for i, part := range parts {
 
-		// add time to allowed time
-		if part == "wtime" {
+		switch part {
+
+		// our time remaining on the clock
+		case "wtime":
 			if pos.isWhiteTurn {
-				timeGain, _ := strconv.Atoi(parts[i+1])
-				allowedTimeMs += timeGain
+				timeRemainingMs = getGoCommandValue(parts, i)
 			}
-		}
+			hasTimeControl = true
 
-		// add time to allowed time
-		if part == "btime" {
+		case "btime":
 			if !pos.isWhiteTurn {
-				timeGain, _ := strconv.Atoi(parts[i+1])
-				allowedTimeMs += timeGain
+				timeRemainingMs = getGoCommandValue(parts, i)
 			}
-		}
+			hasTimeControl = true
 
-		// flag so we know there is increment or not
-		if part == "winc" {
+		// our increment per move
+		case "winc":
 			if pos.isWhiteTurn {
-				hasIncrement = true
+				incrementMs = getGoCommandValue(parts, i)
 			}
-		}
 
-		// flag so we know there is increment or not
-		if part == "binc" {
+		case "binc":
 			if !pos.isWhiteTurn {
-				hasIncrement = true
+				incrementMs = getGoCommandValue(parts, i)
 			}
-		}
+
+		// moves until the next time control (not sent for sudden death)
+		case "movestogo":
+			movesToGo = getGoCommandValue(parts, i)
 
 		// set the time to this specifically
-		if part == "movetime" {
-			specificTime, _ := strconv.Atoi(parts[i+1])
-			allowedTimeMs = specificTime
-			allocateTime = false
+		case "movetime":
+			moveTimeMs = getGoCommandValue(parts, i)
+			hasTimeControl = true
+
+		// search up to the given depth only
+		case "depth":
+			limits.setMaxDepth(getGoCommandValue(parts, i))
+			hasOtherLimit = true
+
+		// search the given number of nodes only
+		case "nodes":
+			limits.maxNodes = getGoCommandValue(parts, i)
+			hasOtherLimit = true
+
+		// search until a mate in the given number of moves is found
+		case "mate":
+			limits.mateInMoves = getGoCommandValue(parts, i)
+			hasOtherLimit = true
+
+		// search infinitely, until the "stop" command is received
+		case "infinite":
+			isInfinite = true
+
+		// search on the opponent's time, until the "ponderhit" or "stop" command is received
+		case "ponder":
+			isPonder = true
+
+		// only search the given moves at the root
+		// the moves follow the token, up to the first token that is not a legal move in the position
+		case "searchmoves":
+			for _, moveStr := range parts[i+1:] {
+				move := pos.getUCIMove(moveStr)
+				if move == BLANK_MOVE {
+					break
+				}
+				limits.searchMoves = append(limits.searchMoves, move)
+			}
 		}
+	}
 
-		// search infinitely
-		// note: the engine still needs to properly implement stop, so this command will likely give errors for now
-		if part == "infinite" {
-			allowedTimeMs = 1000000 // 1000 sec
-			allocateTime = false
-		}
+	// calculate the time we have for the search
+	var timeForSearch int
+	if isInfinite {
+		timeForSearch = INFINITE_SEARCH_TIME_MS
+
+	} else if moveTimeMs > 0 {
+		timeForSearch = moveTimeMs
+
+	} else if !hasTimeControl && hasOtherLimit {
+		// searches limited only by depth, nodes or mate don't have a time limit
+		timeForSearch = INFINITE_SEARCH_TIME_MS
+
+	} else {
+		timeForSearch = pos.allocateSearchTime(timeRemainingMs, incrementMs, movesToGo)
 	}
 
-	// after getting total time, we allocate time for a search
-	// we estimate the game stage using the stage from the position
-	// we can take a bit longer in the endgame (normally less moves to play because of less pieces but also less time remaining)
-	var timeFactor int
-	if hasIncrement {
-		if pos.evalMidVsEndStage >= 20 { // opening
-			timeFactor = 30
-		} else if pos.evalMidVsEndStage >= 16 { // opening-middlegame
-			timeFactor = 22
-		} else if pos.evalMidVsEndStage >= 12 { // middlegame
-			timeFactor = 16
-		} else if pos.evalMidVsEndStage >= 8 { // middlegame-endgame
-			timeFactor = 12
-		} else if pos.evalMidVsEndStage >= 4 { // endgame
-			timeFactor = 10
-		} else { // endgame try checkmate
-			timeFactor = 8
+	// keep back the move overhead from timed searches, so that the best move reaches the GUI in time
+	if timeForSearch != INFINITE_SEARCH_TIME_MS {
+		timeForSearch -= getUCIOptionSpinValue(UCI_OPTION_MOVE_OVERHEAD)
+		if timeForSearch < 0 {
+			timeForSearch = 0
 		}
-	} else { // if there is no increment, we just set a constant factor
-		timeFactor = 60
 	}
 
-	// calculate the time we have for the search
-	var timeForSearch int
-	if allocateTime {
-		timeForSearch = allowedTimeMs / timeFactor
+	// we then do the search with the calculated time and the other limits
+	// when pondering, the calculated time is only used once "ponderhit" is received
+	if isPonder {
+		limits.ponder = true
+		limits.ponderHitTimeMs = timeForSearch
+		limits.timeMs = INFINITE_SEARCH_TIME_MS
 	} else {
-		timeForSearch = allowedTimeMs
+		limits.timeMs = timeForSearch
 	}
+	pos.searchForBestMove(limits)
 
-	// we then do the search with the calculated time
-	pos.searchForBestMove(timeForSearch)
+	// while pondering we may not send the best move before "ponderhit" or "stop" is received, even if the search finished early
+	// if "ponderhit" is received after the search finished, we already have our best move and send it immediately
+	if isPonder {
+		pos.searchControl.waitForStopOrPonderHit()
+	}
+
+	// in infinite mode we may not send the best move before being told to stop
+	// the search can finish early (for example when a checkmate is found or the max depth is reached), so we wait for the "stop" command
+	if isInfinite {
+		pos.searchControl.waitForStopRequest()
+	}
 
 	// we then get the best move from the search
 	success := false
@@ -830,8 +959,11 @@ func (pos *Position) command_go(command string) (string, bool) {
 		// the issue seems to be a mismatch between the timing of the Lichess bot receiving the draw
 		// and sending the search command to to the engine for the next move
 		// so it seems like this issue is not really an issue, but we still need to better manage this in a future update
+		// if the search was restricted to certain moves, we take the first of those moves instead
 		pos.generateLegalMoves()
-		if pos.quietMovesCounter > 0 {
+		if len(limits.searchMoves) > 0 {
+			bestMove = limits.searchMoves[0]
+		} else if pos.quietMovesCounter > 0 {
 			bestMove = pos.quietMoves[0]
 		} else {
 			bestMove = pos.threatMoves[0]
@@ -841,15 +973,78 @@ func (pos *Position) command_go(command string) (string, bool) {
 		success = true
 	}
 
-	// convert the best move to a format in uci and return it, and the success flag
-	moveFromStr := getStringFromSq(bestMove.getFromSq())
-	moveToStr := getStringFromSq(bestMove.getToSq())
-	promoteStr := getPromotionStringFromType(bestMove.getPromotionType())
+	// convert the best move to a format in uci
+	output := "bestmove " + getUCIMoveString(bestMove)
 
-	output := "bestmove " + moveFromStr + moveToStr + promoteStr
+	// add the reply we expect from the opponent (taken from the principal variation), so the GUI can let us ponder on it
+	if success {
+		ponderMove := pos.getPonderMove()
+		if ponderMove != BLANK_MOVE {
+			output += " ponder " + getUCIMoveString(ponderMove)
+		}
+	}
+
+	// return the output and the success flag
 	return output, success
 }
 
+// returns the number following the token at the given index of the go command, or 0 if there is no valid number
+func getGoCommandValue(parts []string, index int) int {
+	if index+1 >= len(parts) {
+		return 0
+	}
+	value, err := strconv.Atoi(parts[index+1])
+	if err != nil {
+		return 0
+	}
+	return value
+}
+
+// allocates the time for a search from the time remaining on our clock
+// we first estimate the number of moves we still need to play with the remaining time
+// for sudden death, we use a constant number of moves
+// with increment, we can use more of the remaining time, because the increment is added again after each move
+// we then estimate the moves left from the game stage (less moves to play in the endgame because of less pieces, but also less time remaining)
+// with a classical time control, the moves left are at most the number of moves until the next time control
+// the time for the search is then our share of the remaining time, plus most of the increment
+func (pos *Position) allocateSearchTime(timeRemainingMs int, incrementMs int, movesToGo int) int {
+
+	var movesLeft int
+	if incrementMs > 0 {
+		if pos.evalMidVsEndStage >= 20 { // opening
+			movesLeft = 30
+		} else if pos.evalMidVsEndStage >= 16 { // opening-middlegame
+			movesLeft = 22
+		} else if pos.evalMidVsEndStage >= 12 { // middlegame
+			movesLeft = 16
+		} else if pos.evalMidVsEndStage >= 8 { // middlegame-endgame
+			movesLeft = 12
+		} else if pos.evalMidVsEndStage >= 4 { // endgame
+			movesLeft = 10
+		} else { // endgame try checkmate
+			movesLeft = 8
+		}
+	} else { // if there is no increment, we just set a constant number of moves
+		movesLeft = 60
+	}
+
+	// with a classical time control, the remaining time only needs to last until the next time control
+	// we still keep one extra move in reserve, so that we don't run out of time on the last move before the time control
+	if movesToGo > 0 && movesToGo+1 < movesLeft {
+		movesLeft = movesToGo + 1
+	}
+
+	timeForSearch := timeRemainingMs/movesLeft + (incrementMs * 3 / 4)
+
+	// never use more than most of the remaining time, even with a large increment
+	maxTimeForSearch := timeRemainingMs * 8 / 10
+	if timeForSearch > maxTimeForSearch {
+		timeForSearch = maxTimeForSearch
+	}
+
+	return timeForSearch
+}
+
 // --------------------------------------------------------- Stop -----------------------------------------------
 /*
 GUI to engine:
@@ -938,7 +1133,13 @@ Engine to GUI:
 		The engine should only send this if the option "UCI_ShowCurrLine" is set to true.
 */
 func (pos *Position) command_stop() {
-	// nothing extra needed, we already set a stop flag for searches, and return a "bestmove"
+
+	// set the stop flag for the search running in the background
+	// the search then returns the best move from the last completed iteration, and sends the "bestmove" command
+	pos.searchControl.requestStop()
+
+	// wait until the best move was sent, before processing any further commands
+	pos.searchControl.waitForSearchToFinish()
 }
 
 // --------------------------------------------------------- Ponder Hit -----------------------------------------------
@@ -950,7 +1151,9 @@ GUI to engine:
     the user has played. The engine should continue searching but switch from pondering to normal search.
 */
 func (pos *Position) command_ponderHit() {
-	// this engine cannot ponder
+	// the running ponder search picks up the ponder hit at its next time check, and continues as a normal search
+	// the best move is sent by the search once it is done
+	pos.searchControl.requestPonderHit()
 }
 
 // --------------------------------------------------------- Quit -----------------------------------------------
@@ -961,5 +1164,8 @@ GUI to engine:
     quit the program as soon as possible
 */
 func (pos *Position) command_quit() {
-	// nothing extra needed
+
+	// stop any search running in the background, and let it finish cleanly before the program exits
+	pos.searchControl.requestStop()
+	pos.searchControl.waitForSearchToFinish()
 }
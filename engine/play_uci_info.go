@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// --------------------------------------------------------------------------------------------------------------------
+// ------------------------------------------------------- UCI Info ---------------------------------------------------
+// --------------------------------------------------------------------------------------------------------------------
+/*
+While searching, the engine sends "info" lines to the GUI:
+- After each completed iteration: depth, seldepth, score, nodes, nps, time, hashfull and the pv (see search_pv.go).
+  With the "MultiPV" option, one line is sent for each of the best lines, with the index of the line (see search_multipv.go).
+- During long iterations (at least every UCI_INFO_INTERVAL_MS): nodes, nps, time and hashfull.
+- At the end of the search (directly before "bestmove"): the final node statistics.
+
+Scores are sent from the engine's point of view (the side to move at the root).
+Checkmate scores are encoded in the search as the win value less a penalty for each ply of the game (see negamax),
+so we convert them back to "mate N", where N is the number of moves (not plies) until the mate.
+N is negative if the engine is getting mated.
+*/
+
+const (
+	UCI_INFO_INTERVAL_MS int64 = 1000 // minimum time between the regular "info nodes" lines during an iteration
+)
+
+// converts a search score to the number of moves (not plies) until the mate, and whether the score is a mate score
+// the number of moves is negative if the side to move at the root is getting mated
+// the root ply is the game ply of the position the search started from
+func getMovesToMate(score int, rootPly int) (int, bool) {
+
+	// the side to move at the root is mating
+	if score > MAX_CHECKMATE {
+		matePly := (WHITE_WIN_VALUE - score) / PLY_PENALTY
+		pliesToMate := matePly - rootPly
+		return (pliesToMate + 1) / 2, true
+	}
+
+	// the side to move at the root is getting mated
+	if score < MIN_CHECKMATE {
+		matePly := (WHITE_WIN_VALUE + score) / PLY_PENALTY
+		pliesToMate := matePly - rootPly
+		return 0 - (pliesToMate / 2), true
+	}
+
+	return 0, false
+}
+
+// converts a search score to the uci score string ("cp <x>" or "mate <y>")
+func getUCIScoreString(score int, rootPly int) string {
+	movesToMate, isMate := getMovesToMate(score, rootPly)
+	if isMate {
+		return "mate " + strconv.Itoa(movesToMate)
+	}
+	return "cp " + strconv.Itoa(score)
+}
+
+// returns the nodes per second for the given nodes and time
+func getNodesPerSecond(nodes int, timeMs int64) int {
+	if timeMs <= 0 {
+		return 0
+	}
+	return int(int64(nodes) * 1000 / timeMs)
+}
+
+// sends the info of a completed iteration to the GUI, with one line for each of the best lines
+// the "multipv" token is only sent when more than one line is searched
+func (pos *Position) printIterationInfo(depth int) {
+
+	timeMs := time.Since(pos.logSearch.startTime).Milliseconds()
+	nodes := pos.getSearchNodes()
+
+	for i, line := range pos.multiPVLines {
+
+		output := "info depth " + strconv.Itoa(depth)
+		output += " seldepth " + strconv.Itoa(pos.logSearch.selDepth)
+		if pos.searchMultiPV > 1 {
+			output += " multipv " + strconv.Itoa(i+1)
+		}
+		output += " score " + getUCIScoreString(line.score, pos.ply)
+		output += " nodes " + strconv.Itoa(nodes)
+		output += " nps " + strconv.Itoa(getNodesPerSecond(nodes, timeMs))
+		output += " time " + strconv.FormatInt(timeMs, 10)
+		output += " hashfull " + strconv.Itoa(pos.tt.getHashFull())
+
+		if len(line.pv) > 0 {
+			output += " pv " + getPVString(line.pv)
+		} else if line.move != BLANK_MOVE {
+			output += " pv " + getUCIMoveString(line.move)
+		}
+
+		fmt.Printf("%v\n", output)
+	}
+
+	pos.timeLastInfo = time.Now()
+}
+
+// sends the node statistics of the search so far to the GUI
+func (pos *Position) printNodesInfo() {
+
+	timeMs := time.Since(pos.logSearch.startTime).Milliseconds()
+	nodes := pos.getSearchNodes()
+
+	output := "info nodes " + strconv.Itoa(nodes)
+	output += " nps " + strconv.Itoa(getNodesPerSecond(nodes, timeMs))
+	output += " time " + strconv.FormatInt(timeMs, 10)
+	output += " hashfull " + strconv.Itoa(pos.tt.getHashFull())
+
+	fmt.Printf("%v\n", output)
+	pos.timeLastInfo = time.Now()
+}
@@ -0,0 +1,256 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// --------------------------------------------------------------------------------------------------------------------
+// ------------------------------------------------------ UCI Options -------------------------------------------------
+// --------------------------------------------------------------------------------------------------------------------
+/*
+The engine settings that can be changed from the GUI are declared once in the option registry below.
+- The "uci" command advertises every option in the registry (name, type, default, min, max and combo values).
+- The "setoption" command looks up the option (case-insensitive) and stores the new value.
+- The rest of the engine reads the current settings from the registry using the getters below.
+
+Some options also need an action when they change (for example resizing the hash table).
+Those options have an "onChange" function that is called after the new value is stored.
+Buttons don't have a value, they only call the "onChange" function.
+*/
+
+const (
+	UCI_OPTION_TYPE_CHECK  int = 0 // checkbox: true or false
+	UCI_OPTION_TYPE_SPIN   int = 1 // integer in a range
+	UCI_OPTION_TYPE_COMBO  int = 2 // one of the predefined strings
+	UCI_OPTION_TYPE_BUTTON int = 3 // no value, only an action
+	UCI_OPTION_TYPE_STRING int = 4 // free text
+)
+
+var uciOptionTypeToText = [5]string{"check", "spin", "combo", "button", "string"}
+
+const (
+	UCI_OPTION_HASH          string = "Hash"
+	UCI_OPTION_CLEAR_HASH    string = "Clear Hash"
+	UCI_OPTION_THREADS       string = "Threads"
+	UCI_OPTION_MULTI_PV      string = "MultiPV"
+	UCI_OPTION_PONDER        string = "Ponder"
+	UCI_OPTION_OWN_BOOK      string = "OwnBook"
+	UCI_OPTION_MOVE_OVERHEAD string = "Move Overhead"
+)
+
+type UCIOption struct {
+	name         string              // name of the option as sent to the GUI
+	optionType   int                 // check, spin, combo, button or string
+	defaultValue string              // default value as sent to the GUI
+	min          int                 // minimum value (spin only)
+	max          int                 // maximum value (spin only)
+	vars         []string            // predefined values (combo only)
+	value        string              // current value of the option
+	onChange     func(pos *Position) // optional action after the value changed or the button was pressed
+}
+
+// ------------------------------------------------------ Registry -------------------------------------------------
+
+var uciOptions []*UCIOption
+var initUCIOptionsWasDone bool = false
+
+// creates the option registry with all values set to their defaults
+// options are only created once, so that settings received before "ucinewgame" are kept
+func initUCIOptions() {
+	if initUCIOptionsWasDone {
+		return
+	}
+
+	uciOptions = []*UCIOption{
+		// size of the transposition table in MB
+		{name: UCI_OPTION_HASH, optionType: UCI_OPTION_TYPE_SPIN, defaultValue: strconv.Itoa(TT_SIZE_DEFAULT_IN_MB), min: 1, max: 32768,
+			onChange: (*Position).resizeTTFromOptions},
+
+		// clears the transposition table and other tables learned during previous searches
+		{name: UCI_OPTION_CLEAR_HASH, optionType: UCI_OPTION_TYPE_BUTTON,
+			onChange: (*Position).clearSearchTables},
+
+		// number of search threads
+		{name: UCI_OPTION_THREADS, optionType: UCI_OPTION_TYPE_SPIN, defaultValue: "1", min: 1, max: 256},
+
+		// number of best lines to search and report
+		{name: UCI_OPTION_MULTI_PV, optionType: UCI_OPTION_TYPE_SPIN, defaultValue: "1", min: 1, max: 64},
+
+		// whether the GUI allows pondering
+		{name: UCI_OPTION_PONDER, optionType: UCI_OPTION_TYPE_CHECK, defaultValue: "false"},
+
+		// the engine has no opening book of its own, the book is left to the GUI
+		{name: UCI_OPTION_OWN_BOOK, optionType: UCI_OPTION_TYPE_CHECK, defaultValue: "false"},
+
+		// time in ms kept back from each search to allow for communication delays with the GUI
+		{name: UCI_OPTION_MOVE_OVERHEAD, optionType: UCI_OPTION_TYPE_SPIN, defaultValue: "10", min: 0, max: 5000},
+	}
+
+	// set the current values to the defaults
+	for _, option := range uciOptions {
+		option.value = option.defaultValue
+	}
+
+	initUCIOptionsWasDone = true
+}
+
+// returns the option with the given name (case-insensitive), or nil if the option does not exist
+func getUCIOption(name string) *UCIOption {
+	for _, option := range uciOptions {
+		if strings.EqualFold(option.name, name) {
+			return option
+		}
+	}
+	return nil
+}
+
+// ------------------------------------------------------ Getters -------------------------------------------------
+
+// returns the current value of a spin option
+func getUCIOptionSpinValue(name string) int {
+	initUCIOptions()
+	value, _ := strconv.Atoi(getUCIOption(name).value)
+	return value
+}
+
+// returns the current value of a check option
+func getUCIOptionCheckValue(name string) bool {
+	initUCIOptions()
+	return getUCIOption(name).value == "true"
+}
+
+// returns the current value of a combo or string option
+func getUCIOptionStringValue(name string) string {
+	initUCIOptions()
+	return getUCIOption(name).value
+}
+
+// ------------------------------------------------------ GUI Communication -------------------------------------------------
+
+// returns the line sent to the GUI to advertise the option, for example:
+// "option name Hash type spin default 12 min 1 max 32768"
+func (option *UCIOption) getUCIOptionString() string {
+	output := "option name " + option.name + " type " + uciOptionTypeToText[option.optionType]
+
+	switch option.optionType {
+	case UCI_OPTION_TYPE_CHECK:
+		output += " default " + option.defaultValue
+
+	case UCI_OPTION_TYPE_SPIN:
+		output += " default " + option.defaultValue + " min " + strconv.Itoa(option.min) + " max " + strconv.Itoa(option.max)
+
+	case UCI_OPTION_TYPE_COMBO:
+		output += " default " + option.defaultValue
+		for _, v := range option.vars {
+			output += " var " + v
+		}
+
+	case UCI_OPTION_TYPE_STRING:
+		if option.defaultValue == "" {
+			output += " default <empty>"
+		} else {
+			output += " default " + option.defaultValue
+		}
+	}
+
+	return output
+}
+
+// prints all the options in the registry to the GUI
+func printUCIOptions() {
+	initUCIOptions()
+	for _, option := range uciOptions {
+		fmt.Printf("%v\n", option.getUCIOptionString())
+	}
+}
+
+// parses a "setoption name <id> [value <x>]" command into the name and value parts
+// the tokens "name" and "value" are case-insensitive, and both the name and the value can contain spaces
+func parseSetOptionCommand(command string) (string, string) {
+	parts := strings.Fields(command)
+
+	nameIndex := -1
+	valueIndex := -1
+	for i, part := range parts {
+		if nameIndex == -1 && strings.EqualFold(part, "name") {
+			nameIndex = i
+		} else if nameIndex != -1 && valueIndex == -1 && strings.EqualFold(part, "value") {
+			valueIndex = i
+		}
+	}
+
+	// without a name, there is nothing to set
+	if nameIndex == -1 {
+		return "", ""
+	}
+
+	// the name is everything between "name" and "value" (or the end of the command if there is no value)
+	if valueIndex == -1 {
+		return strings.Join(parts[nameIndex+1:], " "), ""
+	}
+	return strings.Join(parts[nameIndex+1:valueIndex], " "), strings.Join(parts[valueIndex+1:], " ")
+}
+
+// sets the option to the new value received from the GUI
+// unknown options and invalid values are ignored as required by the uci protocol
+// spin values outside the allowed range are clamped to the range
+func (pos *Position) setUCIOption(name string, value string) {
+	initUCIOptions()
+
+	option := getUCIOption(name)
+	if option == nil {
+		return
+	}
+
+	switch option.optionType {
+	case UCI_OPTION_TYPE_CHECK:
+		if strings.EqualFold(value, "true") {
+			option.value = "true"
+		} else if strings.EqualFold(value, "false") {
+			option.value = "false"
+		} else {
+			return
+		}
+
+	case UCI_OPTION_TYPE_SPIN:
+		spinValue, err := strconv.Atoi(value)
+		if err != nil {
+			return
+		}
+		if spinValue < option.min {
+			spinValue = option.min
+		}
+		if spinValue > option.max {
+			spinValue = option.max
+		}
+		option.value = strconv.Itoa(spinValue)
+
+	case UCI_OPTION_TYPE_COMBO:
+		foundVar := false
+		for _, v := range option.vars {
+			if strings.EqualFold(v, value) {
+				option.value = v
+				foundVar = true
+			}
+		}
+		if !foundVar {
+			return
+		}
+
+	case UCI_OPTION_TYPE_STRING:
+		if value == "<empty>" {
+			value = ""
+		}
+		option.value = value
+
+	case UCI_OPTION_TYPE_BUTTON:
+		// buttons have no value, we only do the action below
+	}
+
+	// finally do the action linked to the option (if any)
+	if option.onChange != nil {
+		option.onChange(pos)
+	}
+}
@@ -103,10 +103,41 @@ type Position struct {
 	bestMoveSoFar Move // used to store the best move in the search
 	bestMove      Move // store the best move from the search after each iteration
 
+	// principal variation variables
+	pvTable      [KILLER_TABLE_SIZE][KILLER_TABLE_SIZE]Move // triangular table: the row of each ply stores the best line found from that ply onwards
+	pvLength     [KILLER_TABLE_SIZE]int                     // the index after the last move stored in the row of each ply of the pv table
+	pvLine       []Move                                     // principal variation from the last completed iteration (starting with the best move)
+	pvFollow     bool                                       // whether the next node searched is still on the principal variation of the previous iteration
+	pvFollowLine []Move                                     // the line of the previous iteration followed by the current root search
+
+	// multipv variables
+	searchMultiPV           int           // number of best lines to search and report (from the "MultiPV" uci option)
+	multiPVLines            []MultiPVLine // best lines from the last completed iteration, sorted from best to worst
+	searchExcludedRootMoves []Move        // root moves excluded from the current root search, because their lines were already found
+
 	// search time management variables
 	timeNodesCount       int       // increases by 1 at each node, to check time at a certain amount of nodes
 	timeStartingTime     time.Time // starts when a search is initiated
 	timeTotalAllowedTime int       // in milliseconds, what is the total allowed time for the search
+	timeLastInfo         time.Time // when the last "info" line was sent to the GUI
+	searchMaxNodes       int       // maximum nodes for the search, 0 if there is no node limit
+	searchRootMoves      []Move    // only search these moves at the root, all moves are searched if empty
+
+	// ponder search variables
+	searchPondering          bool // whether the search is still pondering (searching on the opponent's time without a time limit)
+	timePonderHitAllowedTime int  // in milliseconds, the total allowed time for the search once "ponderhit" is received
+
+	// search output variables
+	searchPrintInfo bool // whether the search sends uci "info" lines to the GUI
+
+	// search control shared with the uci input loop (allows stopping a search running in the background)
+	// and with the other search threads
+	searchControl  *SearchControl
+	isHelperThread bool // whether this position is searched by a helper thread (lazy smp), instead of the main search thread
+
+	// tables learned during searches, kept between the moves of a game
+	tt           *TranspositionTable // transposition table (allocated with the size from the "Hash" uci option)
+	historyTable *HistoryTable       // history table for ordering quiet moves
 
 	// killer heuristic variables
 	killerMoves [KILLER_TABLE_SIZE][2]Move // table to save killer moves
@@ -203,10 +234,23 @@ func (pos *Position) reset() {
 	pos.bestMoveSoFar = BLANK_MOVE
 	pos.bestMove = BLANK_MOVE
 
+	// reset the principal variation
+	pos.pvLine = nil
+	pos.pvFollow = false
+	pos.pvFollowLine = nil
+
+	// reset the multipv lines
+	pos.multiPVLines = nil
+	pos.searchExcludedRootMoves = nil
+
 	// reset the time management variables
 	// pos.timeStartingTime: will reset once a search is started
 	pos.timeNodesCount = 0
 	pos.timeTotalAllowedTime = 0
+	pos.searchMaxNodes = 0
+	pos.searchRootMoves = nil
+	pos.searchPondering = false
+	pos.timePonderHitAllowedTime = 0
 
 	// reset the killer move table
 	// not done here, done before every search
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"math"
 	"time"
 )
 
@@ -76,6 +77,8 @@ func initQSDepthLimits() {
 
 const (
 	INFINITY int = 1000000000 // 1 bil
+
+	INFINITE_SEARCH_TIME_MS int = math.MaxInt // allowed time for searches that only end when told to stop
 )
 
 var (
@@ -83,7 +86,8 @@ var (
 )
 
 // function to initiate a search on the current position and store the best move
-func (pos *Position) searchForBestMove(timeLimitMs int) {
+// the search runs until one of the search limits is reached (see search_limits.go)
+func (pos *Position) searchForBestMove(limits SearchLimits) {
 
 	pos.logTime.allLogTypes[LOG_ONCE_SEARCH_STARTUP].start()
 
@@ -93,31 +97,61 @@ func (pos *Position) searchForBestMove(timeLimitMs int) {
 	// set the starting time of the search
 	pos.logSearch.start()
 
-	// reset the depth, we start searching at depth 2
-	depth := 1
+	// reset the depth, we start searching at depth 2 (or at the depth limit if it is lower)
+	depth := MIN_SEARCH_DEPTH - 1
+	if limits.maxDepth < MIN_SEARCH_DEPTH {
+		depth = limits.maxDepth - 1
+	}
 
 	// reset the position's best move
 	pos.bestMove = BLANK_MOVE
 	pos.bestMoveSoFar = BLANK_MOVE
 
+	// reset the principal variation (the pv from the previous search starts with our previous move, so it can't be followed)
+	pos.pvLine = nil
+	pos.pvFollow = false
+	pos.pvFollowLine = nil
+
+	// reset the best lines, and get the number of best lines to search from the "MultiPV" option
+	pos.multiPVLines = nil
+	pos.searchExcludedRootMoves = nil
+	pos.searchMultiPV = getUCIOptionSpinValue(UCI_OPTION_MULTI_PV)
+
 	// reset time management nodes
 	pos.timeNodesCount = 0
 	pos.timeStartingTime = time.Now()
-	pos.timeTotalAllowedTime = timeLimitMs
+	pos.timeTotalAllowedTime = limits.timeMs
+	pos.searchMaxNodes = limits.maxNodes
+	pos.searchRootMoves = limits.searchMoves
+	pos.searchPondering = limits.ponder
+	pos.timePonderHitAllowedTime = limits.ponderHitTimeMs
+	pos.timeLastInfo = time.Now()
 
 	// reset the killer moves table
 	pos.resetKillerMoveTable()
 
-	// create a new transposition table for the search
-	tt := getNewTT()
+	// get the transposition table with the size from the "Hash" option
+	// the table is kept from previous searches, we only start a new generation for the replacement of entries
+	pos.resizeTTFromOptions()
+	pos.tt.newSearch()
+	tt := pos.tt
 
-	// create a new history table for the search
-	ht := getNewHistoryTable()
+	// get the history table, also kept from previous searches
+	if pos.historyTable == nil {
+		pos.historyTable = getNewHistoryTable()
+	}
+	ht := pos.historyTable
+
+	// start the helper threads with the number of threads from the "Threads" option (see search_smp.go)
+	pos.startHelperThreads(getUCIOptionSpinValue(UCI_OPTION_THREADS))
 
 	pos.logTime.allLogTypes[LOG_ONCE_SEARCH_STARTUP].stop()
 
+	// with insufficient material at the root, all the root moves are scored as a draw (see negamax)
+	isRootInsufficientMaterial := pos.isInsufficientMaterial()
+
 	// do an iterative deepening search
-	for depth < MAX_DEPTH {
+	for depth < limits.maxDepth {
 
 		// increase the depth
 		depth += 1
@@ -128,31 +162,52 @@ func (pos *Position) searchForBestMove(timeLimitMs int) {
 			qsDepth = qsDepthLimitTable[depth]
 		}
 
-		// set the starting ply
-		ply := 0
-
-		// do the search
-		_, terminated := pos.negamax(depth, depth, 0-INFINITY, INFINITY, tt, ht, qsDepth, ply, false)
+		// do the search, with one root search for each of the best lines (see search_multipv.go)
+		lines, terminated := pos.searchMultiPVLines(depth, qsDepth, tt, ht)
 
 		// store the best move from the search only after each iteration, and continue with the next iteration
 		// in case of terminated searches in the middle of a search, we can't use that move, and exit immediately
-		// we will definitely hit at least one iteration (say about 400 nodes) at depth 2 with 0 quiescence depth
+		// we will definitely hit at least one iteration (say about 400 nodes) at depth 2 (or 1) with 0 quiescence depth
 		// so we will have one best move before the time node limit is checked
 		if !terminated {
-			pos.bestMove = pos.bestMoveSoFar
-			pos.bestMoveSoFar = BLANK_MOVE
+			pos.multiPVLines = lines
+			pos.bestMove = lines[0].move
+			pos.pvLine = lines[0].pv
 
 			pos.logSearch.depth = depth
 			pos.logSearch.qsDepth = qsDepth
 			pos.logSearch.logIteration()
 
+			// send the results of the completed iteration to the GUI
+			if pos.searchPrintInfo {
+				pos.printIterationInfo(depth)
+			}
+
+			// stop early if we were searching for a mate and found it
+			if limits.isMateLimitReached(lines[0].score, pos.ply) {
+				break
+			}
+
+			// stop after the first iteration with insufficient material, deeper iterations only find the same draw again
+			if isRootInsufficientMaterial {
+				break
+			}
+
 		} else {
 			break
 		}
 	}
 
+	// stop the helper threads, the best move is only taken from the main thread
+	pos.stopHelperThreads()
+
 	// finally, log the time taken
 	pos.logSearch.stop()
+
+	// and send the final node statistics to the GUI (the best move is sent directly after this)
+	if pos.searchPrintInfo {
+		pos.printNodesInfo()
+	}
 }
 
 // --------------------------------------------------------------------------------------------------------------------
@@ -172,28 +227,73 @@ const (
 )
 
 // return the score, along with a flag for whether the search was aborted
-func (pos *Position) negamax(initialDepth int, currentDepth int, alpha int, beta int, tt *TranspositionTable, ht *HistoryTable, qsDepth int, ply int, parentWasNull bool) (int, bool) {
+func (pos *Position) negamax(initialDepth int, currentDepth int, alpha int, beta int, tt *TranspositionTable, ht *HistoryTable, qsDepth int, ply int, parentWasNull bool, excludedMove Move) (int, bool) {
 
 	// -------------------------------------------------------- Ply -----------------------------------------------------
 	// ply is the depth since the root, independent of any changes to currentDepth (such as check extensions)
 	// ply is used to make sure the side to move and actual depth from the root stays correct, for example for killer moves
 	ply += 1
 
+	// track the deepest node reached from the root (the selective depth)
+	if ply-1 > pos.logSearch.selDepth {
+		pos.logSearch.selDepth = ply - 1
+	}
+
+	// ------------------------------------------------- Principal Variation --------------------------------------------
+	// start with an empty pv line for this node
+	// and get the move to try first if this node is still on the principal variation of the previous iteration
+	pos.resetPV(ply)
+	pvMove := pos.getPVMoveToFollow(ply)
+
+	// ------------------------------------------------------ Node Hash -------------------------------------------------
+	// the key of the node in the TT, which is adjusted for exclusion searches (see search_singular.go)
+	nodeHash := pos.getNodeHash(excludedMove)
+
 	// -------------------------------------------------- Time Management -----------------------------------------------
 	// count the nodes searched for time management checks
 	// if a certain number of nodes have been reached, pause the search and check whether the time has elapsed
 	pos.timeNodesCount += 1
 
+	// if the search has a node limit, stop the search once the limit is reached
+	// like for the time limit, we only stop after the first iteration is complete, so that we always have a best move
+	// the limit counts the nodes of all the search threads
+	if pos.searchMaxNodes > 0 && pos.bestMove != BLANK_MOVE && pos.getSearchNodes() >= pos.searchMaxNodes {
+		return 0, true
+	}
+
 	if pos.timeNodesCount >= NODES_BEFORE_CHECK_INTERRUPT {
 		// reset the node count ready for the next check
 		pos.timeNodesCount = 0
 
+		// helper threads (lazy smp) share their node count with the main thread, and only stop when the main thread tells them to
+		if pos.isHelperThread {
+			pos.searchControl.addHelperNodes(NODES_BEFORE_CHECK_INTERRUPT)
+			if pos.searchControl.isHelperStopRequested() {
+				return 0, true
+			}
+		}
+
+		// if we were pondering and the opponent played the expected move, we continue as a normal timed search
+		if pos.searchPondering && pos.searchControl.isPonderHitReceived() {
+			pos.convertPonderToNormalSearch()
+		}
+
 		// calculate time since start
 		// if we are over the allowed time, stop the search
 		timeSince := time.Since(pos.timeStartingTime).Milliseconds()
 		if timeSince >= int64(pos.timeTotalAllowedTime) {
 			return 0, true
 		}
+
+		// if the search was requested to stop from outside (for example the uci "stop" command), stop the search
+		if pos.searchControl.isStopRequested() {
+			return 0, true
+		}
+
+		// send regular node statistics to the GUI during long iterations
+		if pos.searchPrintInfo && time.Since(pos.timeLastInfo).Milliseconds() >= UCI_INFO_INTERVAL_MS {
+			pos.printNodesInfo()
+		}
 	}
 
 	// ---------------------------------------------------- Node Statistics -----------------------------------------------
@@ -210,19 +310,24 @@ func (pos *Position) negamax(initialDepth int, currentDepth int, alpha int, beta
 	alphaOriginal := alpha
 
 	// set a variable to catch the TT move if present
+	// we also keep the TT entry for the singular extension test
 	hashMove := BLANK_MOVE
+	var hashEntry TTEntry
+	hashEntryFound := false
 
-	// we only check the TT for non-quiescence nodes
-	// because we only save non-quiescence nodes in the TT
-	// this can be changed later if needed
-	if currentDepth > 0 {
+	// we check the TT for all nodes except the qs leaf nodes (they only return the evaluation)
+	// quiescence nodes are stored in tiers below all non-quiescence nodes (see search_tt.go)
+	if currentDepth > qsDepth {
 
 		pos.logTime.allLogTypes[LOG_SEARCH_TT_PROBE].start()
 		pos.logSearch.depthLogs[nodeType].ttProbe++
 
-		ttEntry, success := tt.getTTEntry(pos.hashOfPos)
+		ttEntry, success := tt.getTTEntry(nodeHash)
 		if success { // if there is a node in the TT
-			if ttEntry.depth >= uint8(currentDepth) { // and if the depth is at least as deep as the current search
+
+			// if the depth is at least as deep as the current search
+			// we don't use TT cutoffs at the root, because the TT is kept between searches and we always need a best move from the root
+			if isTTDepthEnough(ttEntry.depth, getTTDepth(currentDepth, qsDepth)) && currentDepth != initialDepth {
 
 				// if the flag is EXACT and the value is within our current bounds, we can use it
 				if ttEntry.flag == TT_FLAG_EXACT {
@@ -249,6 +354,8 @@ func (pos *Position) negamax(initialDepth int, currentDepth int, alpha int, beta
 
 			// set the hash move if we found a TT Entry but did not get an early cutoff
 			hashMove = ttEntry.move
+			hashEntry = ttEntry
+			hashEntryFound = true
 			pos.logSearch.depthLogs[nodeType].ttRetrievedHashMove++
 		}
 
@@ -261,14 +368,27 @@ func (pos *Position) negamax(initialDepth int, currentDepth int, alpha int, beta
 	// if there is not a TT hit, we need to start with work on the current node
 	// first, we generate all legal moves
 	// we can then determine if the game is over (no legal moves is checkmate or stalemate)
-	pos.generateLegalMoves()
+	// at qs nodes, we only keep the quiet moves that qs searches (see move_gen.go):
+	// - at the first qs ply (depth 0), the quiet moves that give check
+	// - at the deeper qs plies, no quiet moves
+	// - when in check, all the evasions
+	moveGenMode := MOVE_GEN_MODE_ALL
+	if currentDepth == 0 {
+		moveGenMode = MOVE_GEN_MODE_CHECKS
+	} else if currentDepth < 0 {
+		moveGenMode = MOVE_GEN_MODE_EVASIONS
+	}
+	pos.generateLegalMovesForMode(moveGenMode)
 	pos.logSearch.depthLogs[nodeType].generatedLegalMovesFull++
 
 	// _____________________________ Game State ______________________________
 	// once we have generated at least some legal moves, we check whether the game is over
 	// if it is over, we return with the game over score
+	// with insufficient material, we still search the root, because the GUI can ask for a move without claiming the draw
+	// all the moves from the root are then scored as a draw
 	pos.getGameStateAndStore()
-	if pos.gameState != STATE_ONGOING {
+	isRootInsufficientMaterial := currentDepth == initialDepth && pos.gameState == STATE_DRAW_INSUFFICIENT_MATERIAL
+	if pos.gameState != STATE_ONGOING && !isRootInsufficientMaterial {
 		switch pos.gameState {
 
 		case STATE_WIN_WHITE:
@@ -285,7 +405,7 @@ func (pos *Position) negamax(initialDepth int, currentDepth int, alpha int, beta
 				return 0 - (BLACK_WIN_VALUE + (pos.ply * PLY_PENALTY)), false
 			}
 
-		case STATE_DRAW_STALEMATE, STATE_DRAW_50_MOVE_RULE:
+		case STATE_DRAW_STALEMATE, STATE_DRAW_50_MOVE_RULE, STATE_DRAW_INSUFFICIENT_MATERIAL:
 			return 0, false
 
 		case STATE_DRAW_3_FOLD_REPETITION:
@@ -309,6 +429,9 @@ func (pos *Position) negamax(initialDepth int, currentDepth int, alpha int, beta
 	// ------------------------------------------------------------- Evaluation --------------------------------------------------------
 	// we now evaluate the position at qs nodes
 
+	// the stand pat score of qs nodes, used for delta pruning
+	qsStandPat := 0 - INFINITY
+
 	// ________________________________ QS LEAF NODES _______________________________
 	// if the game is not over, and we are at the leaf nodes, we return the eval score
 	if currentDepth <= qsDepth {
@@ -330,7 +453,9 @@ func (pos *Position) negamax(initialDepth int, currentDepth int, alpha int, beta
 		// if we are at a quiescence node but not a leaf node, we use the evaluation as a floor for alpha
 		// this is done for the case that there is no threat/capture moves, so we at least return the evaluation
 		// this also allows beta cutoffs before we loop over all the moves
-	} else if currentDepth <= 0 {
+		// when in check, we can't stand pat (the check might be checkmate), so we search all the evasions instead
+		// that way checkmates at the horizon of the search are found
+	} else if currentDepth <= 0 && !inCheck {
 
 		pos.evalPosAfter()
 		var nodeEval int
@@ -343,9 +468,18 @@ func (pos *Position) negamax(initialDepth int, currentDepth int, alpha int, beta
 		}
 
 		pos.logSearch.depthLogs[nodeType].qsOtherNodes++
+		qsStandPat = nodeEval
 
 		// beta (UPPERBOUND) is not changed in this node, so if it is already above that, return beta
+		// the stand pat score is a lower bound of the node, so we store beta as the lowest bound for next searches
 		if nodeEval >= beta {
+			pos.logTime.allLogTypes[LOG_SEARCH_TT_STORE].start()
+
+			tt.storeNewTTEntry(nodeHash, BLANK_MOVE, int32(beta), getTTDepth(currentDepth, qsDepth), TT_FLAG_LOWERBOUND)
+
+			pos.logTime.allLogTypes[LOG_SEARCH_TT_STORE].stop()
+			pos.logSearch.depthLogs[nodeType].ttStoreLower++
+
 			pos.logSearch.depthLogs[nodeType].qsStandPatBetaCuts++
 			return beta, false
 		}
@@ -356,6 +490,11 @@ func (pos *Position) negamax(initialDepth int, currentDepth int, alpha int, beta
 			pos.logSearch.depthLogs[nodeType].qsStandPatAlphaRaises++
 			alpha = nodeEval
 		}
+
+		// _____________________________ QS IN CHECK NODES _____________________________
+		// no stand pat, the evasions are searched below
+	} else if currentDepth <= 0 {
+		pos.logSearch.depthLogs[nodeType].qsInCheckNodes++
 	}
 
 	// ------------------------------------------- Static Null Move Pruning / Reverse Futility Pruning ---------------------------------------
@@ -366,7 +505,9 @@ func (pos *Position) negamax(initialDepth int, currentDepth int, alpha int, beta
 	// we also don't test this if beta is close to checkmate
 	// we do this for all nodes that are not qs, however the margin increases for each depth
 
-	if currentDepth > 0 && !inCheck && initialDepth > 2 && beta < MAX_CHECKMATE && currentDepth != initialDepth {
+	// we also don't do this in exclusion searches (see search_singular.go)
+
+	if currentDepth > 0 && !inCheck && initialDepth > 2 && beta < MAX_CHECKMATE && currentDepth != initialDepth && excludedMove == BLANK_MOVE {
 
 		// evaluate the node
 		pos.evalPosAfter()
@@ -410,6 +551,7 @@ func (pos *Position) negamax(initialDepth int, currentDepth int, alpha int, beta
 	// we also don't do null moves while in QS
 	// we also don't try null moves at the root
 	// we also don't allow CONSECUTIVE null moves (two null moves after each other)
+	// we also don't try null moves in exclusion searches (see search_singular.go)
 
 	// ___ Restrictions: Eval ___
 	// we also only try null moves if the eval is above beta
@@ -417,7 +559,7 @@ func (pos *Position) negamax(initialDepth int, currentDepth int, alpha int, beta
 	// this will also prevent beta mate scores from influencing the result, because eval is much smaller than the mate score
 
 	// ___ Null Move Pruning ___
-	if currentDepth >= 4 && currentDepth != initialDepth {
+	if currentDepth >= 4 && currentDepth != initialDepth && excludedMove == BLANK_MOVE {
 
 		// evaluate the node
 		var nodeEval int
@@ -439,7 +581,7 @@ func (pos *Position) negamax(initialDepth int, currentDepth int, alpha int, beta
 
 			// make the null move, get the search score, and undo the null move
 			pos.makeNullMove()
-			nullMoveScore, terminated := pos.negamax(initialDepth, currentDepth-nullMoveReduction-1, 0-beta, 0-beta+1, tt, ht, qsDepth, ply, true)
+			nullMoveScore, terminated := pos.negamax(initialDepth, currentDepth-nullMoveReduction-1, 0-beta, 0-beta+1, tt, ht, qsDepth, ply, true, BLANK_MOVE)
 			nullMoveValue := 0 - nullMoveScore
 			pos.undoMove()
 
@@ -461,6 +603,52 @@ func (pos *Position) negamax(initialDepth int, currentDepth int, alpha int, beta
 		}
 	}
 
+	// ----------------------------------------------------------- ProbCut ---------------------------------------------------------
+	// if a good capture beats beta by a margin in a shallow null window search, we expect the node to fail high and prune it (see search_probcut.go)
+	// we only try this at null window nodes that are not the root, when we are not in check, and when beta is not a checkmate score
+	// we also don't try this in exclusion searches (see search_singular.go)
+	if currentDepth >= PROBCUT_MIN_DEPTH && currentDepth != initialDepth && excludedMove == BLANK_MOVE &&
+		!inCheck && beta-alpha <= 1 && beta > MIN_CHECKMATE && beta < MAX_CHECKMATE {
+
+		probCutBeta := getProbCutBeta(beta)
+		probCutDepth := getProbCutSearchDepth(currentDepth)
+
+		// if the TT already tells us that the node does not reach the ProbCut beta, we don't try it
+		ttRulesOut := hashEntryFound && hashEntry.flag != TT_FLAG_LOWERBOUND &&
+			hashEntry.depth >= getTTDepth(probCutDepth, qsDepth) && int(hashEntry.value) < probCutBeta
+
+		if !ttRulesOut && pos.threatMovesCounter > 0 {
+			pos.logSearch.depthLogs[nodeType].probCutTries++
+
+			// only the good captures (according to SEE) are tried, the moves are copies so searching them won't overwrite them
+			goodThreatMoves, _ := pos.getOrderedThreatMovesNormalNodes(ht)
+			for _, move := range goodThreatMoves {
+
+				// make the capture, get the shallow null window search score, and undo the capture
+				pos.makeMove(move)
+				score, terminated := pos.negamax(initialDepth, probCutDepth, 0-probCutBeta, 0-probCutBeta+1, tt, ht, qsDepth, ply, false, BLANK_MOVE)
+				moveValue := 0 - score
+				pos.undoMove()
+
+				// if the search was terminated, return with a zero value
+				if terminated {
+					return 0, true
+				}
+
+				// the capture beats beta by the margin, so we prune the node
+				if moveValue >= probCutBeta {
+					pos.logSearch.depthLogs[nodeType].probCutSuccesses++
+					return beta, false
+				}
+			}
+
+			// if we don't return early, we need to again generate all legal moves in the position (making and undoing moves reset the legal moves)
+			pos.resetPV(ply)
+			pos.generateLegalMoves()
+			pos.logSearch.depthLogs[nodeType].generatedLegalMovesFull++
+		}
+	}
+
 	/* (commenting out for now: did not give an improvement)
 
 	// ----------------------------------------------------------- Razoring --------------------------------------------------------
@@ -524,14 +712,88 @@ func (pos *Position) negamax(initialDepth int, currentDepth int, alpha int, beta
 
 	*/
 
+	// ----------------------------------------------------------- Singular Extensions --------------------------------------------------------
+	// we test whether the hash move is much better than all other moves with a reduced exclusion search (see search_singular.go)
+	// if it is, we extend the hash move by 1 ply, and if another move also beats a singular beta above beta, we prune the node (multi-cut)
+	// we don't test at the root, in exclusion searches, or when the TT value is a checkmate score
+	// we also limit the extensions by ply, so that chains of singular moves can't grow the tree without end
+	singularExtension := 0
+	if currentDepth >= SINGULAR_MIN_DEPTH && currentDepth != initialDepth && excludedMove == BLANK_MOVE &&
+		hashEntryFound && hashMove != BLANK_MOVE && hashEntry.flag != TT_FLAG_UPPERBOUND &&
+		hashEntry.depth >= getTTDepth(currentDepth-SINGULAR_TT_DEPTH_MARGIN, qsDepth) &&
+		int(hashEntry.value) > MIN_CHECKMATE && int(hashEntry.value) < MAX_CHECKMATE &&
+		ply < initialDepth && ply+currentDepth < MAX_DEPTH {
+
+		singularBeta := getSingularBeta(int(hashEntry.value), currentDepth)
+		pos.logSearch.depthLogs[nodeType].singularSearches++
+
+		// search the same node again without the hash move, with a null window just below the singular beta
+		// we are still at the same node, so we decrease ply by 1, because it will immediately be increased again
+		score, terminated := pos.negamax(initialDepth, getSingularSearchDepth(currentDepth), singularBeta-1, singularBeta, tt, ht, qsDepth, ply-1, parentWasNull, hashMove)
+		if terminated {
+			return 0, true
+		}
+
+		if score < singularBeta {
+			// no other move comes close to the hash move
+			singularExtension = 1
+			pos.logSearch.depthLogs[nodeType].singularExtensions++
+
+		} else if singularBeta >= beta {
+			// another move also fails high, so we expect the node to fail high
+			pos.logSearch.depthLogs[nodeType].singularMultiCuts++
+			return beta, false
+		}
+
+		// the exclusion search overwrote the legal moves and the pv line of this node, so we need to reset them again
+		pos.resetPV(ply)
+		pos.generateLegalMoves()
+		pos.logSearch.depthLogs[nodeType].generatedLegalMovesFull++
+	}
+
+	// ------------------------------------------------ Futility Pruning and Late Move Pruning: Setup -----------------------------------------
+	// we decide here whether quiet moves can be pruned in the move loops below (see search_pruning.go)
+	// we only prune at null window nodes that are not the root or qs, when we are not in check, and when alpha is not a checkmate score
+	isPVNode := beta-alpha > 1
+	canPruneQuietMoves := currentDepth > 0 && currentDepth != initialDepth && !inCheck && !isPVNode && alpha > MIN_CHECKMATE && alpha < MAX_CHECKMATE
+
+	// ___ Frontier Futility Pruning ___
+	// if the static eval plus a margin can't reach alpha, quiet moves are futile
+	futilityPruning := false
+	if canPruneQuietMoves && currentDepth <= FUTILITY_MAX_DEPTH {
+
+		// evaluate the node
+		pos.evalPosAfter()
+		var nodeEval int
+		pos.logSearch.depthLogs[nodeType].evalNode++
+
+		if pos.isWhiteTurn {
+			nodeEval = pos.evalMaterial + pos.evalHeatmaps + pos.evalOther
+		} else {
+			nodeEval = 0 - (pos.evalMaterial + pos.evalHeatmaps + pos.evalOther)
+		}
+
+		futilityPruning = nodeEval+getFutilityMargin(currentDepth) <= alpha
+		if futilityPruning {
+			pos.logSearch.depthLogs[nodeType].futilityNodes++
+		}
+	}
+
+	// ___ Late Move Pruning ___
+	// after this many moves were searched, the remaining quiet moves are pruned (zero means no late move pruning)
+	lateMovePruningCount := 0
+	if canPruneQuietMoves && currentDepth <= LMP_MAX_DEPTH {
+		lateMovePruningCount = getLateMovePruningCount(currentDepth)
+	}
+
 	// ----------------------------------------------------------- Order Moves --------------------------------------------------------
 	// if we are not at a leaf node, we start ordering moves for the search to try optimise cutoffs
 	// we assume there are moves, because if there were no moves, we already would have returned checkmate or stalemate before
 	// we try moves in the following order:
 	// 1. Best Moves (moves from the TT or the best move from the previous iteration)
-	// 2. Good Threat Moves (captures (normal or en-passant captures) or promotions that have a score >= 0)
+	// 2. Good Threat Moves (captures (normal or en-passant captures) or promotions that don't lose material according to SEE)
 	// 3. Killer Moves (quiet moves that caused a cutoff in a sibling node)
-	// 4. Bad Threat Moves (captures (normal or en-passant captures) or promotions that have a score < 0)
+	// 4. Bad Threat Moves (captures (normal or en-passant captures) or promotions that lose material according to SEE)
 	// 5. Other Quiet Moves (rest of the moves not included above)
 
 	// ___________________________________ THREAT MOVES ___________________________________
@@ -544,7 +806,7 @@ func (pos *Position) negamax(initialDepth int, currentDepth int, alpha int, beta
 	var copyOfBadThreatMoves []Move
 
 	if currentDepth > 0 {
-		copyOfGoodThreatMoves, copyOfBadThreatMoves = pos.getOrderedThreatMovesNormalNodes()
+		copyOfGoodThreatMoves, copyOfBadThreatMoves = pos.getOrderedThreatMovesNormalNodes(ht)
 	} else {
 		copyOfGoodThreatMoves, copyOfBadThreatMoves = pos.getOrderedThreatMovesQsNodes()
 	}
@@ -552,11 +814,21 @@ func (pos *Position) negamax(initialDepth int, currentDepth int, alpha int, beta
 	pos.logTime.allLogTypes[LOG_SEARCH_ORDER_THREAT_MOVES].stop()
 	pos.logSearch.depthLogs[nodeType].orderThreatMoves++
 
+	// ___________________________________ QS: SEE PRUNING ___________________________________
+	// in quiescence, we don't search captures that lose material according to SEE (see search_see.go)
+	// the side to move can always stand pat instead, so these captures are very unlikely to raise alpha
+	// when in check we don't prune, because standing pat is not a real option
+	if currentDepth <= 0 && !inCheck {
+		pos.logSearch.depthLogs[nodeType].qsSEEPrunedMoves += len(copyOfBadThreatMoves)
+		copyOfBadThreatMoves = nil
+	}
+
 	// ___________________________________ QUIET MOVES ___________________________________
 	// we only create quiet moves at non-quiescence nodes
 
+	// at qs nodes, the move generator only kept the quiet checks or the evasions (see move_gen.go)
 	var copyOfQuietMoves []Move
-	if currentDepth > 0 { // non-quiescence nodes
+	if pos.quietMovesCounter > 0 {
 
 		pos.logTime.allLogTypes[LOG_SEARCH_COPY_QUIET_MOVES].start()
 
@@ -568,6 +840,23 @@ func (pos *Position) negamax(initialDepth int, currentDepth int, alpha int, beta
 		pos.logSearch.depthLogs[nodeType].copyQuietMoves++
 	}
 
+	// ___________________________________ ROOT MOVES ___________________________________
+	// if the search is restricted to certain moves at the root ("go searchmoves"), we remove all other moves
+	// the hash move and pv move are only tried if they are found in the remaining moves below, so they are also restricted
+	if currentDepth == initialDepth && pos.isRootRestricted() {
+		copyOfGoodThreatMoves = pos.filterRootMoves(copyOfGoodThreatMoves)
+		copyOfBadThreatMoves = pos.filterRootMoves(copyOfBadThreatMoves)
+		copyOfQuietMoves = pos.filterRootMoves(copyOfQuietMoves)
+	}
+
+	// ___________________________________ EXCLUDED MOVE ___________________________________
+	// in an exclusion search, the excluded move is not searched (see search_singular.go)
+	if excludedMove != BLANK_MOVE {
+		copyOfGoodThreatMoves = removeExcludedMove(copyOfGoodThreatMoves, excludedMove)
+		copyOfBadThreatMoves = removeExcludedMove(copyOfBadThreatMoves, excludedMove)
+		copyOfQuietMoves = removeExcludedMove(copyOfQuietMoves, excludedMove)
+	}
+
 	// ___________________________________ BEST MOVES: HASH ___________________________________
 	// we have certain guesses for the best move, regardless of the threat vs quiet move split
 	// we test these best moves before threat and quiet moves
@@ -638,80 +927,62 @@ func (pos *Position) negamax(initialDepth int, currentDepth int, alpha int, beta
 		pos.logTime.allLogTypes[LOG_SEARCH_ORDER_HASH_MOVES].stop()
 	}
 
-	// ___________________________________ BEST MOVES: ITERATIVE DEEPENING  ___________________________________
-	// we also use the previous iterative deepening search's best move first, if we are at the root
-	// this code will therefore only run once each time the depth is increased (acceptable because the code takes long)
-	// we call this last after other move ordering, because we put the previous iteration's best move first regardless of other move ordering
+	// ___________________________________ BEST MOVES: PRINCIPAL VARIATION  ___________________________________
+	// we also use the principal variation of the previous iterative deepening search, if this node is still on that line
+	// at the root, the pv move is the best move of the previous iteration
+	// further down, the pv move is the move the previous iteration expected to be played in this position
+	// we call this last after other move ordering, because we put the pv move first regardless of other move ordering
+	// the pv move could be missing from the moves of this node (for example a quiet move at a qs node), then we don't reorder anything
 
-	if currentDepth == initialDepth { // if we are at the root depth
-		bestMovePreviousIteration := pos.bestMove
+	if pvMove != BLANK_MOVE && pvMove != hashMove {
 
-		// we need to first get a best move from iterative deepening before we can put it at the front (assuming we don't have a hash move already as that move)
-		if bestMovePreviousIteration != BLANK_MOVE && bestMovePreviousIteration != hashMove {
+		pos.logTime.allLogTypes[LOG_SEARCH_ORDER_PREVIOUS_ITERATION_MOVES].start()
+		pos.logSearch.depthLogs[nodeType].orderIterativeDeepeningMove++
 
-			pos.logTime.allLogTypes[LOG_SEARCH_ORDER_PREVIOUS_ITERATION_MOVES].start()
-			pos.logSearch.depthLogs[nodeType].orderIterativeDeepeningMove++
+		// ________________________ GOOD THREAT MOVES ________________________
+		// find the index of the pv move
+		pvIndexGoodThreatMoves := -1
+		for index, move := range copyOfGoodThreatMoves {
+			if move == pvMove {
+				pvIndexGoodThreatMoves = index
+			}
+		}
 
-			// ________________________ GOOD THREAT MOVES ________________________
-			// find the index of the best move
-			bestIndexGoodThreatMoves := -1
-			for index, move := range copyOfGoodThreatMoves {
-				if move == bestMovePreviousIteration {
-					bestIndexGoodThreatMoves = index
+		// ________________________ BAD THREAT MOVES ________________________
+		pvIndexBadThreatMoves := -1
+		if pvIndexGoodThreatMoves == -1 {
+			for index, move := range copyOfBadThreatMoves {
+				if move == pvMove {
+					pvIndexBadThreatMoves = index
 				}
 			}
+		}
 
-			if bestIndexGoodThreatMoves != -1 { // best move is a threat move
-
-				// remove the best move from the original position (FROM THREAT MOVES)
-				copyOfGoodThreatMoves = append(copyOfGoodThreatMoves[:bestIndexGoodThreatMoves], copyOfGoodThreatMoves[bestIndexGoodThreatMoves+1:]...)
-
-				// append the best move at the start of the list of moves after ordering (TO BEST MOVES)
-				copyOfBestMoves = append([]Move{bestMovePreviousIteration}, copyOfBestMoves...)
-
-				// ________________________ BAD THREAT MOVES ________________________
-			} else {
-
-				// find the index of the best move
-				bestIndexBadThreatMoves := -1
-				for index, move := range copyOfBadThreatMoves {
-					if move == bestMovePreviousIteration {
-						bestIndexBadThreatMoves = index
-					}
+		// ________________________ QUIET MOVES ________________________
+		pvIndexQuietMoves := -1
+		if pvIndexGoodThreatMoves == -1 && pvIndexBadThreatMoves == -1 {
+			for index, move := range copyOfQuietMoves {
+				if move == pvMove {
+					pvIndexQuietMoves = index
 				}
+			}
+		}
 
-				if bestIndexBadThreatMoves != -1 { // best move is a threat move
-
-					// remove the best move from the original position (FROM THREAT MOVES)
-					copyOfBadThreatMoves = append(copyOfBadThreatMoves[:bestIndexBadThreatMoves], copyOfBadThreatMoves[bestIndexBadThreatMoves+1:]...)
-
-					// append the best move at the start of the list of moves after ordering (TO BEST MOVES)
-					copyOfBestMoves = append([]Move{bestMovePreviousIteration}, copyOfBestMoves...)
-
-					// ________________________ QUIET MOVES ________________________
-				} else { // best move is a quiet move
-
-					// find the index of the best move
-					bestIndexQuietMoves := -1
-					for index, move := range copyOfQuietMoves {
-						if move == bestMovePreviousIteration {
-							bestIndexQuietMoves = index
-						}
-					}
-
-					// remove the best move from the original position (FROM QUIET MOVES)
-					copyOfQuietMoves = append(copyOfQuietMoves[:bestIndexQuietMoves], copyOfQuietMoves[bestIndexQuietMoves+1:]...)
-
-					// append the best move at the start of the list of moves after ordering (TO BEST MOVES)
-					copyOfBestMoves = append([]Move{bestMovePreviousIteration}, copyOfBestMoves...)
-				}
+		// remove the pv move from the list it was found in, and put it at the start of the list of best moves
+		if pvIndexGoodThreatMoves != -1 {
+			copyOfGoodThreatMoves = append(copyOfGoodThreatMoves[:pvIndexGoodThreatMoves], copyOfGoodThreatMoves[pvIndexGoodThreatMoves+1:]...)
+			copyOfBestMoves = append([]Move{pvMove}, copyOfBestMoves...)
 
-				// ________________________ HASH MOVES ________________________
-				// if the best move is not in threat or quiet moves, we assume it is already in best moves as the hash move
+		} else if pvIndexBadThreatMoves != -1 {
+			copyOfBadThreatMoves = append(copyOfBadThreatMoves[:pvIndexBadThreatMoves], copyOfBadThreatMoves[pvIndexBadThreatMoves+1:]...)
+			copyOfBestMoves = append([]Move{pvMove}, copyOfBestMoves...)
 
-				pos.logTime.allLogTypes[LOG_SEARCH_ORDER_PREVIOUS_ITERATION_MOVES].stop()
-			}
+		} else if pvIndexQuietMoves != -1 {
+			copyOfQuietMoves = append(copyOfQuietMoves[:pvIndexQuietMoves], copyOfQuietMoves[pvIndexQuietMoves+1:]...)
+			copyOfBestMoves = append([]Move{pvMove}, copyOfBestMoves...)
 		}
+
+		pos.logTime.allLogTypes[LOG_SEARCH_ORDER_PREVIOUS_ITERATION_MOVES].stop()
 	}
 
 	// ------------------------------------------------------- Main Search: Setup -------------------------------------------------------
@@ -719,24 +990,39 @@ func (pos *Position) negamax(initialDepth int, currentDepth int, alpha int, beta
 	bestMove := BLANK_MOVE
 	pos.logSearch.depthLogs[nodeType].loopedOverMoves++
 
+	// we count the moves searched over all the move loops, only the first move is searched with the full window (see search_pvs.go)
+	movesSearched := 0
+
 	// get the side for storing history moves
 	side := SIDE_BLACK
 	if pos.isWhiteTurn {
 		side = SIDE_WHITE
 	}
 
+	// get the previous moves for the counter move and continuation history
+	previousMoves := pos.getPreviousMoves()
+
 	// ------------------------------------------------------- Main Search: Best Moves --------------------------------------------------
 	// start the search and iterate over each move
 	bestMovesTried := 0
 	for _, move := range copyOfBestMoves {
 		bestMovesTried++
 
+		// ___________________________________________ Follow the Principal Variation ___________________________________
+		// the child node is only on the principal variation of the previous iteration if we search the pv move
+		pos.pvFollow = move == pvMove
+
+		// ___________________________________________ Singular Extension ___________________________________
+		// the hash move is searched 1 ply deeper if it is singular
+		newDepth := currentDepth - 1
+		if move == hashMove {
+			newDepth += singularExtension
+		}
+
 		// ___________________________________________ Make and Undo Move ___________________________________
-		// play the move, get the score of the node, and undo the move again
-		pos.makeMove(move)
-		score, terminated := pos.negamax(initialDepth, currentDepth-1, 0-beta, 0-alpha, tt, ht, qsDepth, ply, false)
-		moveValue := 0 - score
-		pos.undoMove()
+		// play the move, get the score of the node (with principal variation search), and undo the move again
+		moveValue, terminated := pos.searchMovePVS(move, initialDepth, newDepth, alpha, beta, tt, ht, qsDepth, ply, movesSearched, nodeType)
+		movesSearched++
 
 		// if the search was terminated, return with a zero value
 		if terminated {
@@ -758,8 +1044,9 @@ func (pos *Position) negamax(initialDepth int, currentDepth int, alpha int, beta
 		// so first test for that before storing it as a killer move or in the history table
 		moveType := move.getMoveType()
 		promotionType := move.getPromotionType()
+		// quiet moves at qs nodes (quiet checks and evasions) don't update the killer moves and history
 		isQuiet := false
-		if (moveType == MOVE_TYPE_QUIET || moveType == MOVE_TYPE_CASTLE) && (promotionType == PROMOTION_NONE) {
+		if (moveType == MOVE_TYPE_QUIET || moveType == MOVE_TYPE_CASTLE) && (promotionType == PROMOTION_NONE) && currentDepth > 0 {
 			isQuiet = true
 		}
 
@@ -774,21 +1061,19 @@ func (pos *Position) negamax(initialDepth int, currentDepth int, alpha int, beta
 				}
 
 				// ___________ HISTORY MOVES ___________
-				ht.goodBetaMove(move, currentDepth, side)
+				ht.goodBetaMove(move, currentDepth, side, previousMoves)
 			}
 
 			// ___________ NORMAL CODE ___________
-			if currentDepth > 0 {
-				// store TT entries for non-quiescence nodes
-				// if we have a beta cut, this node failed high
-				// so beta is the lowest bound for next searches
-				pos.logTime.allLogTypes[LOG_SEARCH_TT_STORE].start()
+			// store TT entries
+			// if we have a beta cut, this node failed high
+			// so beta is the lowest bound for next searches
+			pos.logTime.allLogTypes[LOG_SEARCH_TT_STORE].start()
 
-				tt.storeNewTTEntry(pos.hashOfPos, move, int32(beta), uint8(currentDepth), TT_FLAG_LOWERBOUND)
+			tt.storeNewTTEntry(nodeHash, move, int32(beta), getTTDepth(currentDepth, qsDepth), TT_FLAG_LOWERBOUND)
 
-				pos.logTime.allLogTypes[LOG_SEARCH_TT_STORE].stop()
-				pos.logSearch.depthLogs[nodeType].ttStoreLower++
-			}
+			pos.logTime.allLogTypes[LOG_SEARCH_TT_STORE].stop()
+			pos.logSearch.depthLogs[nodeType].ttStoreLower++
 
 			pos.logSearch.depthLogs[nodeType].bestMovesCutoffs++
 			pos.logSearch.depthLogs[nodeType].bestMovesTriedBeforeCuts += bestMovesTried
@@ -801,16 +1086,17 @@ func (pos *Position) negamax(initialDepth int, currentDepth int, alpha int, beta
 			// ___________ NORMAL CODE ___________
 			alpha = moveValue
 			bestMove = move
+			pos.updatePV(move, ply)
 
 			if isQuiet {
 				// ___________ HISTORY MOVES ___________
-				ht.goodAlphaMove(move, currentDepth, side)
+				ht.goodAlphaMove(move, currentDepth, side, previousMoves)
 			}
 
 		} else {
 			if isQuiet {
 				// ___________ HISTORY MOVES ___________
-				ht.badAlphaMove(move, currentDepth, side)
+				ht.badAlphaMove(move, currentDepth, side, previousMoves)
 			}
 		}
 	}
@@ -824,14 +1110,22 @@ func (pos *Position) negamax(initialDepth int, currentDepth int, alpha int, beta
 	// start the search and iterate over each move
 	threatGoodMovesTried := 0
 	for _, move := range copyOfGoodThreatMoves {
+
+		// ___________________________________________ Delta Pruning ___________________________________
+		// in qs, we skip captures that can't raise alpha, even if they win the captured piece for free (see search_pruning.go)
+		if currentDepth <= 0 && !inCheck && alpha > MIN_CHECKMATE && alpha < MAX_CHECKMATE {
+			if qsStandPat+pos.getThreatMoveMaxGain(move)+DELTA_PRUNING_MARGIN <= alpha {
+				pos.logSearch.depthLogs[nodeType].deltaPrunedMoves++
+				continue
+			}
+		}
+
 		threatGoodMovesTried++
 
 		// ___________________________________________ Make and Undo Move ___________________________________
-		// play the move, get the score of the node, and undo the move again
-		pos.makeMove(move)
-		score, terminated := pos.negamax(initialDepth, currentDepth-1, 0-beta, 0-alpha, tt, ht, qsDepth, ply, false)
-		moveValue := 0 - score
-		pos.undoMove()
+		// play the move, get the score of the node (with principal variation search), and undo the move again
+		moveValue, terminated := pos.searchMovePVS(move, initialDepth, currentDepth-1, alpha, beta, tt, ht, qsDepth, ply, movesSearched, nodeType)
+		movesSearched++
 
 		// if the search was terminated, return with a zero value
 		if terminated {
@@ -851,17 +1145,20 @@ func (pos *Position) negamax(initialDepth int, currentDepth int, alpha int, beta
 		// beta cutoff
 		if moveValue >= beta {
 
+			// ___________ CAPTURE HISTORY ___________
 			if currentDepth > 0 {
-				// store TT entries for non-quiescence nodes
-				// if we have a beta cut, this node failed high
-				// so beta is the lowest bound for next searches
-				pos.logTime.allLogTypes[LOG_SEARCH_TT_STORE].start()
+				ht.goodBetaCapture(move, pos.getCapturedPiece(move), currentDepth, side)
+			}
 
-				tt.storeNewTTEntry(pos.hashOfPos, move, int32(beta), uint8(currentDepth), TT_FLAG_LOWERBOUND)
+			// store TT entries
+			// if we have a beta cut, this node failed high
+			// so beta is the lowest bound for next searches
+			pos.logTime.allLogTypes[LOG_SEARCH_TT_STORE].start()
 
-				pos.logTime.allLogTypes[LOG_SEARCH_TT_STORE].stop()
-				pos.logSearch.depthLogs[nodeType].ttStoreLower++
-			}
+			tt.storeNewTTEntry(nodeHash, move, int32(beta), getTTDepth(currentDepth, qsDepth), TT_FLAG_LOWERBOUND)
+
+			pos.logTime.allLogTypes[LOG_SEARCH_TT_STORE].stop()
+			pos.logSearch.depthLogs[nodeType].ttStoreLower++
 
 			pos.logSearch.depthLogs[nodeType].threatGoodMovesCutoffs++
 			pos.logSearch.depthLogs[nodeType].threatGoodMovesTriedBeforeCuts += threatGoodMovesTried
@@ -872,6 +1169,11 @@ func (pos *Position) negamax(initialDepth int, currentDepth int, alpha int, beta
 		if moveValue > alpha {
 			alpha = moveValue
 			bestMove = move
+			pos.updatePV(move, ply)
+
+		} else if currentDepth > 0 {
+			// ___________ CAPTURE HISTORY ___________
+			ht.badAlphaCapture(move, pos.getCapturedPiece(move), currentDepth, side)
 		}
 	}
 
@@ -964,14 +1266,26 @@ func (pos *Position) negamax(initialDepth int, currentDepth int, alpha int, beta
 
 		quietKillerMovesTried := 0
 		for _, move := range copyOfKillerMoves {
+
+			// ___________________________________________ Futility and Late Move Pruning ___________________________________
+			// we skip quiet moves that are very unlikely to raise alpha, unless they give check (see search_pruning.go)
+			if movesSearched > 0 && (futilityPruning || (lateMovePruningCount > 0 && movesSearched >= lateMovePruningCount)) {
+				if !pos.isCheckingMove(move) {
+					if futilityPruning {
+						pos.logSearch.depthLogs[nodeType].futilityPrunedMoves++
+					} else {
+						pos.logSearch.depthLogs[nodeType].lateMovePrunedMoves++
+					}
+					continue
+				}
+			}
+
 			quietKillerMovesTried++
 
 			// ___________________________________________ Make and Undo Move ___________________________________
-			// play the move, get the score of the node, and undo the move again
-			pos.makeMove(move)
-			score, terminated := pos.negamax(initialDepth, currentDepth-1, 0-beta, 0-alpha, tt, ht, qsDepth, ply, false)
-			moveValue := 0 - score
-			pos.undoMove()
+			// play the move, get the score of the node (with principal variation search), and undo the move again
+			moveValue, terminated := pos.searchMovePVS(move, initialDepth, currentDepth-1, alpha, beta, tt, ht, qsDepth, ply, movesSearched, nodeType)
+			movesSearched++
 
 			// if the search was terminated, return with a zero value
 			if terminated {
@@ -998,20 +1312,18 @@ func (pos *Position) negamax(initialDepth int, currentDepth int, alpha int, beta
 				}
 
 				// ___________ HISTORY MOVES ___________
-				ht.goodBetaMove(move, currentDepth, side)
+				ht.goodBetaMove(move, currentDepth, side, previousMoves)
 
 				// ___________ NORMAL CODE ___________
-				if currentDepth > 0 {
-					// store TT entries for non-quiescence nodes
-					// if we have a beta cut, this node failed high
-					// so beta is the lowest bound for next searches
-					pos.logTime.allLogTypes[LOG_SEARCH_TT_STORE].start()
+				// store TT entries
+				// if we have a beta cut, this node failed high
+				// so beta is the lowest bound for next searches
+				pos.logTime.allLogTypes[LOG_SEARCH_TT_STORE].start()
 
-					tt.storeNewTTEntry(pos.hashOfPos, move, int32(beta), uint8(currentDepth), TT_FLAG_LOWERBOUND)
+				tt.storeNewTTEntry(nodeHash, move, int32(beta), getTTDepth(currentDepth, qsDepth), TT_FLAG_LOWERBOUND)
 
-					pos.logTime.allLogTypes[LOG_SEARCH_TT_STORE].stop()
-					pos.logSearch.depthLogs[nodeType].ttStoreLower++
-				}
+				pos.logTime.allLogTypes[LOG_SEARCH_TT_STORE].stop()
+				pos.logSearch.depthLogs[nodeType].ttStoreLower++
 
 				pos.logSearch.depthLogs[nodeType].quietKillerMovesCutoffs++
 				pos.logSearch.depthLogs[nodeType].quietKillerMovesTriedBeforeCuts += quietKillerMovesTried
@@ -1023,14 +1335,15 @@ func (pos *Position) negamax(initialDepth int, currentDepth int, alpha int, beta
 				// ___________ NORMAL CODE ___________
 				alpha = moveValue
 				bestMove = move
+				pos.updatePV(move, ply)
 
 				// ___________ HISTORY MOVES ___________
-				ht.goodAlphaMove(move, currentDepth, side)
+				ht.goodAlphaMove(move, currentDepth, side, previousMoves)
 
 			} else {
 
 				// ___________ HISTORY MOVES ___________
-				ht.badAlphaMove(move, currentDepth, side)
+				ht.badAlphaMove(move, currentDepth, side, previousMoves)
 			}
 		}
 
@@ -1047,11 +1360,9 @@ func (pos *Position) negamax(initialDepth int, currentDepth int, alpha int, beta
 		threatBadMovesTried++
 
 		// ___________________________________________ Make and Undo Move ___________________________________
-		// play the move, get the score of the node, and undo the move again
-		pos.makeMove(move)
-		score, terminated := pos.negamax(initialDepth, currentDepth-1, 0-beta, 0-alpha, tt, ht, qsDepth, ply, false)
-		moveValue := 0 - score
-		pos.undoMove()
+		// play the move, get the score of the node (with principal variation search), and undo the move again
+		moveValue, terminated := pos.searchMovePVS(move, initialDepth, currentDepth-1, alpha, beta, tt, ht, qsDepth, ply, movesSearched, nodeType)
+		movesSearched++
 
 		// if the search was terminated, return with a zero value
 		if terminated {
@@ -1071,17 +1382,20 @@ func (pos *Position) negamax(initialDepth int, currentDepth int, alpha int, beta
 		// beta cutoff
 		if moveValue >= beta {
 
+			// ___________ CAPTURE HISTORY ___________
 			if currentDepth > 0 {
-				// store TT entries for non-quiescence nodes
-				// if we have a beta cut, this node failed high
-				// so beta is the lowest bound for next searches
-				pos.logTime.allLogTypes[LOG_SEARCH_TT_STORE].start()
+				ht.goodBetaCapture(move, pos.getCapturedPiece(move), currentDepth, side)
+			}
 
-				tt.storeNewTTEntry(pos.hashOfPos, move, int32(beta), uint8(currentDepth), TT_FLAG_LOWERBOUND)
+			// store TT entries
+			// if we have a beta cut, this node failed high
+			// so beta is the lowest bound for next searches
+			pos.logTime.allLogTypes[LOG_SEARCH_TT_STORE].start()
 
-				pos.logTime.allLogTypes[LOG_SEARCH_TT_STORE].stop()
-				pos.logSearch.depthLogs[nodeType].ttStoreLower++
-			}
+			tt.storeNewTTEntry(nodeHash, move, int32(beta), getTTDepth(currentDepth, qsDepth), TT_FLAG_LOWERBOUND)
+
+			pos.logTime.allLogTypes[LOG_SEARCH_TT_STORE].stop()
+			pos.logSearch.depthLogs[nodeType].ttStoreLower++
 
 			pos.logSearch.depthLogs[nodeType].threatBadMovesCutoffs++
 			pos.logSearch.depthLogs[nodeType].threatBadMovesTriedBeforeCuts += threatBadMovesTried
@@ -1092,6 +1406,11 @@ func (pos *Position) negamax(initialDepth int, currentDepth int, alpha int, beta
 		if moveValue > alpha {
 			alpha = moveValue
 			bestMove = move
+			pos.updatePV(move, ply)
+
+		} else if currentDepth > 0 {
+			// ___________ CAPTURE HISTORY ___________
+			ht.badAlphaCapture(move, pos.getCapturedPiece(move), currentDepth, side)
 		}
 	}
 
@@ -1102,10 +1421,13 @@ func (pos *Position) negamax(initialDepth int, currentDepth int, alpha int, beta
 
 	// ------------------------------------------------------- Order Moves: Other Quiet Moves --------------------------------------------------
 	// we order other quiet moves based on their history score
-	if currentDepth > 0 && currentDepth != initialDepth {
+	if len(copyOfQuietMoves) > 0 && currentDepth != initialDepth {
 		pos.logTime.allLogTypes[LOG_SEARCH_ORDER_OTHER_QUIET_MOVES].start()
 
-		pos.orderQuietHistoryMoves(copyOfQuietMoves, ht)
+		counterMoveFound := pos.orderQuietHistoryMoves(copyOfQuietMoves, ht, previousMoves)
+		if counterMoveFound {
+			pos.logSearch.depthLogs[nodeType].orderCounterMove++
+		}
 
 		pos.logTime.allLogTypes[LOG_SEARCH_ORDER_OTHER_QUIET_MOVES].stop()
 		pos.logSearch.depthLogs[nodeType].orderOtherQuietMoves++
@@ -1115,9 +1437,11 @@ func (pos *Position) negamax(initialDepth int, currentDepth int, alpha int, beta
 	// start the search and iterate over each move
 
 	// ___________________________________________ Quiescence Moves ___________________________________
-	// at the depth of zero or lower, we only consider threat moves (captures, en-passant and promotions)
-	// we therefore only iterate over quiet moves at depths > 0 (non-quiescence nodes)
-	if currentDepth > 0 {
+	// at the depth of zero or lower, we mainly consider threat moves (captures, en-passant and promotions)
+	// the only quiet moves at qs nodes are the quiet checks at the first qs ply, and the evasions when in check
+	// quiet checks that lose material according to SEE are not searched in qs
+	// we don't update the killer moves and the history at qs nodes
+	if len(copyOfQuietMoves) > 0 {
 
 		// _____________ Late Move Reductions _____________
 
@@ -1167,7 +1491,34 @@ func (pos *Position) negamax(initialDepth int, currentDepth int, alpha int, beta
 
 		quietOtherMovesTried := 0
 		for _, move := range copyOfQuietMoves {
+
+			// ___________________________________________ QS: SEE Pruning ___________________________________
+			if currentDepth <= 0 && !inCheck && !pos.isGoodCaptureSEE(move) {
+				pos.logSearch.depthLogs[nodeType].qsSEEPrunedMoves++
+				continue
+			}
+
+			// ___________________________________________ Futility and Late Move Pruning ___________________________________
+			// we skip quiet moves that are very unlikely to raise alpha, unless they give check (see search_pruning.go)
+			if movesSearched > 0 && (futilityPruning || (lateMovePruningCount > 0 && movesSearched >= lateMovePruningCount)) {
+				if !pos.isCheckingMove(move) {
+					if futilityPruning {
+						pos.logSearch.depthLogs[nodeType].futilityPrunedMoves++
+					} else {
+						pos.logSearch.depthLogs[nodeType].lateMovePrunedMoves++
+					}
+					continue
+				}
+			}
+
 			quietOtherMovesTried++
+			if currentDepth <= 0 {
+				if inCheck {
+					pos.logSearch.depthLogs[nodeType].qsEvasionsTried++
+				} else {
+					pos.logSearch.depthLogs[nodeType].qsQuietChecksTried++
+				}
+			}
 
 			// ___________________________________________ Make and Undo Move ___________________________________
 			// play the move, get the score of the node, and undo the move again
@@ -1178,31 +1529,28 @@ func (pos *Position) negamax(initialDepth int, currentDepth int, alpha int, beta
 			if canDoLMR {
 				// ___________ LMR: Zero-Width Window ___________
 				pos.makeMove(move)
-				score, terminated = pos.negamax(initialDepth, currentDepth-lmr-1, 0-(alpha+1), 0-alpha, tt, ht, qsDepth, ply, false)
+				score, terminated = pos.negamax(initialDepth, currentDepth-lmr-1, 0-(alpha+1), 0-alpha, tt, ht, qsDepth, ply, false, BLANK_MOVE)
 				moveValue = 0 - score
 				pos.undoMove()
 
 				pos.logSearch.depthLogs[nodeType].lmrReducedNodes++
 
 				// ___________ LMR: Fail-High Research ___________
-				if moveValue > alpha {
-					pos.makeMove(move)
-					score, terminated = pos.negamax(initialDepth, currentDepth-1, 0-beta, 0-alpha, tt, ht, qsDepth, ply, false)
-					moveValue = 0 - score
-					pos.undoMove()
+				// the re-search at full depth is done with principal variation search:
+				// first with a null window, and only with the full window if the move still improves alpha
+				if !terminated && moveValue > alpha {
+					moveValue, terminated = pos.searchMovePVS(move, initialDepth, currentDepth-1, alpha, beta, tt, ht, qsDepth, ply, movesSearched, nodeType)
 
 					pos.logSearch.depthLogs[nodeType].lmrReducedNodesFailures++
 				}
 
 			} else {
 				// ___________ No LMR ___________
-				pos.makeMove(move)
-				score, terminated = pos.negamax(initialDepth, currentDepth-1, 0-beta, 0-alpha, tt, ht, qsDepth, ply, false)
-				moveValue = 0 - score
-				pos.undoMove()
+				moveValue, terminated = pos.searchMovePVS(move, initialDepth, currentDepth-1, alpha, beta, tt, ht, qsDepth, ply, movesSearched, nodeType)
 
 				pos.logSearch.depthLogs[nodeType].lmrNonReducedNodes++
 			}
+			movesSearched++
 
 			// if the search was terminated, return with a zero value
 			if terminated {
@@ -1222,27 +1570,35 @@ func (pos *Position) negamax(initialDepth int, currentDepth int, alpha int, beta
 			// beta cutoff
 			if moveValue >= beta {
 
-				// ___________ KILLER MOVES ___________
-				if move != pos.killerMoves[ply][0] { // if we have a unique new killer move
-					pos.killerMoves[ply][1] = pos.killerMoves[ply][0] // move the previous new move to the old move slot
-					pos.killerMoves[ply][0] = move                    // save the current killer move in the new move slot
-				}
+				if currentDepth > 0 {
+					// ___________ KILLER MOVES ___________
+					if move != pos.killerMoves[ply][0] { // if we have a unique new killer move
+						pos.killerMoves[ply][1] = pos.killerMoves[ply][0] // move the previous new move to the old move slot
+						pos.killerMoves[ply][0] = move                    // save the current killer move in the new move slot
+					}
 
-				// ___________ HISTORY MOVES ___________
-				ht.goodBetaMove(move, currentDepth, side)
+					// ___________ COUNTER MOVE AND CONTINUATION HISTORY STATISTICS ___________
+					if move == ht.getCounterMove(side, previousMoves[0]) {
+						pos.logSearch.depthLogs[nodeType].counterMoveCutoffs++
+					}
+					if ht.getContinuationScore(move, side, previousMoves) > 0 {
+						pos.logSearch.depthLogs[nodeType].continuationHistoryCutoffs++
+					}
+
+					// ___________ HISTORY MOVES ___________
+					ht.goodBetaMove(move, currentDepth, side, previousMoves)
+				}
 
 				// ___________ NORMAL CODE ___________
-				if currentDepth > 0 {
-					// store TT entries for non-quiescence nodes
-					// if we have a beta cut, this node failed high
-					// so beta is the lowest bound for next searches
-					pos.logTime.allLogTypes[LOG_SEARCH_TT_STORE].start()
+				// store TT entries
+				// if we have a beta cut, this node failed high
+				// so beta is the lowest bound for next searches
+				pos.logTime.allLogTypes[LOG_SEARCH_TT_STORE].start()
 
-					tt.storeNewTTEntry(pos.hashOfPos, move, int32(beta), uint8(currentDepth), TT_FLAG_LOWERBOUND)
+				tt.storeNewTTEntry(nodeHash, move, int32(beta), getTTDepth(currentDepth, qsDepth), TT_FLAG_LOWERBOUND)
 
-					pos.logTime.allLogTypes[LOG_SEARCH_TT_STORE].stop()
-					pos.logSearch.depthLogs[nodeType].ttStoreLower++
-				}
+				pos.logTime.allLogTypes[LOG_SEARCH_TT_STORE].stop()
+				pos.logSearch.depthLogs[nodeType].ttStoreLower++
 
 				pos.logSearch.depthLogs[nodeType].quietOtherMovesCutoffs++
 				pos.logSearch.depthLogs[nodeType].quietOtherMovesTriedBeforeCuts += quietOtherMovesTried
@@ -1254,13 +1610,16 @@ func (pos *Position) negamax(initialDepth int, currentDepth int, alpha int, beta
 				// ___________ NORMAL CODE ___________
 				alpha = moveValue
 				bestMove = move
+				pos.updatePV(move, ply)
 
 				// ___________ HISTORY MOVES ___________
-				ht.goodAlphaMove(move, currentDepth, side)
+				if currentDepth > 0 {
+					ht.goodAlphaMove(move, currentDepth, side, previousMoves)
+				}
 
-			} else {
+			} else if currentDepth > 0 {
 				// ___________ HISTORY MOVES ___________
-				ht.badAlphaMove(move, currentDepth, side)
+				ht.badAlphaMove(move, currentDepth, side, previousMoves)
 			}
 		}
 
@@ -1272,23 +1631,25 @@ func (pos *Position) negamax(initialDepth int, currentDepth int, alpha int, beta
 
 	// ---------------------------------------------------- TT Store Entry -----------------------------------------------
 	// after iterating over all the moves, we store the node in the TT
-	// we only store TT entries for non-quiescence nodes because they are fully searched
+	// quiescence nodes are stored too, with the qs depth (see search_tt.go)
+	// if the stand pat score raised alpha and no move improved on it, alpha (the stand pat score) is the exact value of the qs node
+	// we don't store the root if the root moves were restricted, because then the value is not the value of the position
 
-	if currentDepth > 0 {
+	if !(currentDepth == initialDepth && pos.isRootRestricted()) {
 
 		pos.logTime.allLogTypes[LOG_SEARCH_TT_STORE].start()
 
 		if alpha > alphaOriginal {
 			// if alpha increased in the search, we know the exact value of the node, because:
 			// we did not fail high, because we already would have had a beta cut before this code
-			tt.storeNewTTEntry(pos.hashOfPos, bestMove, int32(alpha), uint8(currentDepth), TT_FLAG_EXACT)
+			tt.storeNewTTEntry(nodeHash, bestMove, int32(alpha), getTTDepth(currentDepth, qsDepth), TT_FLAG_EXACT)
 			pos.logSearch.depthLogs[nodeType].ttStoreExact++
 
 		} else {
 			// if alpha did not increase in the search, this node failed low
 			// it did not fail high, because no beta cut was found
 			// this node value is therefore the upper bound for next searches
-			tt.storeNewTTEntry(pos.hashOfPos, BLANK_MOVE, int32(alpha), uint8(currentDepth), TT_FLAG_UPPERBOUND)
+			tt.storeNewTTEntry(nodeHash, BLANK_MOVE, int32(alpha), getTTDepth(currentDepth, qsDepth), TT_FLAG_UPPERBOUND)
 			pos.logSearch.depthLogs[nodeType].ttStoreUpper++
 		}
 
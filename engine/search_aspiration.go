@@ -0,0 +1,87 @@
+package main
+
+// --------------------------------------------------------------------------------------------------------------------
+// ------------------------------------------------- Aspiration Windows -----------------------------------------------
+// --------------------------------------------------------------------------------------------------------------------
+/*
+The score of an iteration is usually close to the score of the previous iteration.
+Instead of searching the root with the full window (-INFINITY, INFINITY), we search with a small window around the previous score.
+A smaller window gives more cutoffs, so the iteration is searched faster.
+
+If the score is outside of the window, the search failed and we don't know the real score:
+- Fail low (score <= alpha): all moves are worse than expected, we widen the window downwards and search again.
+- Fail high (score >= beta): a move is better than expected, we widen the window upwards and search again.
+Each time a side of the window fails, the widening of that side doubles.
+Once the widening gets too big, that side of the window becomes the full window, so the re-searches always end.
+
+We only use aspiration windows once the scores are stable enough (from ASPIRATION_MIN_DEPTH onwards),
+and not when the previous score is a mate score (mate scores change with the depth).
+With MultiPV, each line uses a window around the score of the same line in the previous iteration.
+*/
+
+const (
+	ASPIRATION_MIN_DEPTH      int = 5   // first depth searched with an aspiration window
+	ASPIRATION_WINDOW_INITIAL int = 35  // initial distance of alpha and beta from the previous score
+	ASPIRATION_WINDOW_MAX     int = 500 // once the widening is bigger than this, the failed side gets the full window
+)
+
+// searches the root with an aspiration window around the score of the line with the given index in the previous iteration
+// re-searches with a wider window until the score is inside the window
+// returns the score, and whether the search was terminated
+func (pos *Position) searchRootWithAspiration(depth int, qsDepth int, pvIndex int, tt *TranspositionTable, ht *HistoryTable) (int, bool) {
+
+	// ___ Window ___
+	alpha := 0 - INFINITY
+	beta := INFINITY
+	window := ASPIRATION_WINDOW_INITIAL
+
+	if depth >= ASPIRATION_MIN_DEPTH && pvIndex < len(pos.multiPVLines) {
+		previousScore := pos.multiPVLines[pvIndex].score
+		if _, isMate := getMovesToMate(previousScore, pos.ply); !isMate {
+			alpha = previousScore - window
+			beta = previousScore + window
+		}
+	}
+
+	for {
+		// reset the best move and the pv to follow for each re-search
+		pos.bestMoveSoFar = BLANK_MOVE
+		pos.pvFollow = true
+
+		if alpha > 0-INFINITY || beta < INFINITY {
+			pos.logSearch.depthLogs[NODE_TYPE_NORMAL].aspirationSearches++
+		}
+
+		// do the search (the root is at ply 0, negamax increases it to 1)
+		score, terminated := pos.negamax(depth, depth, alpha, beta, tt, ht, qsDepth, 0, false, BLANK_MOVE)
+		if terminated {
+			return 0, true
+		}
+
+		// ___ Fail Low ___
+		if score <= alpha && alpha > 0-INFINITY {
+			pos.logSearch.depthLogs[NODE_TYPE_NORMAL].aspirationFailLows++
+
+			window *= 2
+			alpha = score - window
+			if window > ASPIRATION_WINDOW_MAX || alpha < 0-INFINITY {
+				alpha = 0 - INFINITY
+			}
+			continue
+		}
+
+		// ___ Fail High ___
+		if score >= beta && beta < INFINITY {
+			pos.logSearch.depthLogs[NODE_TYPE_NORMAL].aspirationFailHighs++
+
+			window *= 2
+			beta = score + window
+			if window > ASPIRATION_WINDOW_MAX || beta > INFINITY {
+				beta = INFINITY
+			}
+			continue
+		}
+
+		return score, false
+	}
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// --------------------------------------------------------------------------------------------------------------------
+// ---------------------------------------------------- Search Control ------------------------------------------------
+// --------------------------------------------------------------------------------------------------------------------
+/*
+The UCI specification requires that the engine can always process input from the GUI, even while thinking.
+We therefore run searches started with "go" on their own goroutine, and keep on reading commands in the input loop.
+
+The search control is shared between the input loop and the running search:
+- The input loop sets the stop flag when the search needs to end as soon as possible (for example "stop" or "quit").
+- The search polls the stop flag together with the time management checks in negamax.
+- The input loop can wait for the running search to finish before changing the position again.
+  A search without a time limit (infinite, or ponder before "ponderhit") only ends on "stop",
+  so the input loop stops it first instead of waiting for it, which would block the input loop forever.
+
+The stop flag is atomic, because it is written and read from different goroutines.
+
+The same applies to pondering ("go ponder"), where the engine searches on the opponent's time:
+- The input loop sets the ponder hit flag when the opponent played the expected move ("ponderhit").
+- The search polls the ponder hit flag together with the stop flag, and then continues as a normal timed search.
+
+With more than one search thread (see search_smp.go), the search control is also shared between the main search thread and the helper threads:
+- The main thread sets the helper stop flag once it has finished its search, and waits for the helpers to stop.
+- The helpers add the nodes they searched to a shared counter, so that the main thread can report the nodes of all threads.
+*/
+
+type SearchControl struct {
+	stopFlag   atomic.Bool    // set when the search needs to stop as soon as possible
+	stopSignal chan struct{}  // closed when the stop flag is set, to wake up searches waiting for a "stop" command
+	running    sync.WaitGroup // tracks the search that is running in the background
+	isRunning  atomic.Bool    // set while a search is running in the background
+	isUntimed  atomic.Bool    // set when the running search only ends on "stop" (infinite, or ponder before "ponderhit")
+
+	ponderHitFlag   atomic.Bool   // set when the GUI sends "ponderhit" during a ponder search
+	ponderHitSignal chan struct{} // closed when the ponder hit flag is set, to wake up ponder searches that finished early
+
+	helperStopFlag atomic.Bool    // set by the main search thread when the helper threads need to stop
+	helperNodes    atomic.Int64   // nodes searched by all the helper threads in the current search
+	helpers        sync.WaitGroup // tracks the helper threads that are running
+}
+
+// returns a new search control, ready for a search to start
+func getNewSearchControl() *SearchControl {
+	newSearchControl := SearchControl{}
+	newSearchControl.stopSignal = make(chan struct{})
+	newSearchControl.ponderHitSignal = make(chan struct{})
+	return &newSearchControl
+}
+
+// resets the stop and ponder hit flags before a new search is started
+// this must only be called when no search is running
+func (sc *SearchControl) reset() {
+	sc.stopFlag.Store(false)
+	sc.stopSignal = make(chan struct{})
+	sc.isUntimed.Store(false)
+	sc.ponderHitFlag.Store(false)
+	sc.ponderHitSignal = make(chan struct{})
+}
+
+// marks the search that is about to start as a search that only ends on "stop" (infinite, or ponder before "ponderhit")
+// this must be called after reset, and before the search is started
+func (sc *SearchControl) setUntimed() {
+	sc.isUntimed.Store(true)
+}
+
+// requests the running search to stop as soon as possible
+// the signal channel is only closed once, even if stop is requested multiple times
+func (sc *SearchControl) requestStop() {
+	if sc.stopFlag.CompareAndSwap(false, true) {
+		close(sc.stopSignal)
+	}
+}
+
+// returns true if the search was requested to stop
+func (sc *SearchControl) isStopRequested() bool {
+	return sc.stopFlag.Load()
+}
+
+// blocks until a stop is requested
+// used by infinite searches that finished early, because they may not send a best move before being told to stop
+func (sc *SearchControl) waitForStopRequest() {
+	<-sc.stopSignal
+}
+
+// tells the running ponder search that the opponent played the expected move
+// the signal channel is only closed once, even if "ponderhit" is received multiple times
+func (sc *SearchControl) requestPonderHit() {
+	if sc.ponderHitFlag.CompareAndSwap(false, true) {
+		close(sc.ponderHitSignal)
+	}
+}
+
+// returns true if "ponderhit" was received during the search
+func (sc *SearchControl) isPonderHitReceived() bool {
+	return sc.ponderHitFlag.Load()
+}
+
+// blocks until a stop is requested or "ponderhit" is received
+// used by ponder searches that finished early, because they may not send a best move while still pondering
+func (sc *SearchControl) waitForStopOrPonderHit() {
+	select {
+	case <-sc.stopSignal:
+	case <-sc.ponderHitSignal:
+	}
+}
+
+// ___ Helper Threads ___
+
+// starts the helper search function on its own goroutine and tracks it as a running helper
+// the helper stop flag and node count must be reset (with resetHelpers) before the helpers of a search are started
+func (sc *SearchControl) startHelper(helperFunction func()) {
+	sc.helpers.Add(1)
+	go func() {
+		defer sc.helpers.Done()
+		helperFunction()
+	}()
+}
+
+// resets the helper stop flag and node count before the helpers of a new search are started
+func (sc *SearchControl) resetHelpers() {
+	sc.helperStopFlag.Store(false)
+	sc.helperNodes.Store(0)
+}
+
+// tells the helper threads to stop, and blocks until all of them have stopped
+func (sc *SearchControl) stopHelpersAndWait() {
+	sc.helperStopFlag.Store(true)
+	sc.helpers.Wait()
+}
+
+// returns true if the helper threads were told to stop
+func (sc *SearchControl) isHelperStopRequested() bool {
+	return sc.helperStopFlag.Load()
+}
+
+// adds the nodes searched by a helper thread to the shared node count
+func (sc *SearchControl) addHelperNodes(nodes int) {
+	sc.helperNodes.Add(int64(nodes))
+}
+
+// returns the nodes searched by all the helper threads so far
+func (sc *SearchControl) getHelperNodes() int {
+	return int(sc.helperNodes.Load())
+}
+
+// starts the search function on its own goroutine and tracks it as the running search
+func (sc *SearchControl) startInBackground(searchFunction func()) {
+	sc.running.Add(1)
+	sc.isRunning.Store(true)
+	go func() {
+		defer sc.running.Done()
+		searchFunction()
+		sc.isRunning.Store(false)
+	}()
+}
+
+// returns true if a search is running in the background
+func (sc *SearchControl) isSearchRunning() bool {
+	return sc.isRunning.Load()
+}
+
+// blocks until the search running in the background (if any) has finished
+func (sc *SearchControl) waitForSearchToFinish() {
+	sc.running.Wait()
+}
+
+// stops the running search first if it only ends on "stop", and then blocks until it has finished
+// a ponder search that received "ponderhit" is a normal timed search again, so we let it finish
+func (sc *SearchControl) stopUntimedSearchAndWait() {
+	if sc.isUntimed.Load() && !sc.isPonderHitReceived() {
+		sc.requestStop()
+	}
+	sc.running.Wait()
+}
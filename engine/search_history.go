@@ -8,7 +8,8 @@ import (
 // -------------------------------------------------- Quiet Moves History ---------------------------------------------
 // --------------------------------------------------------------------------------------------------------------------
 /*
-To better sort quiet moves (other than killer moves), we have a history table for each search.
+To better sort quiet moves (other than killer moves), we have a history table.
+The table is kept between the searches (moves) of a game, and is only cleared when a new game starts.
 A history table is independent of depth (whereas killer moves are dependent on the depth).
 The table stores moves in [side][piece][toSq].
 
@@ -20,40 +21,183 @@ The aim is in general to catch good quiet moves.
 We also only do this for depths > 4 (where an earlier cutoff will actually have a big effect).
 The time impact will be negligible because at least 90%+ of normal nodes are at depth <= 4.
 
+Gravity:
+The scores are updated with a "gravity" formula: score += bonus - score * |bonus| / HISTORY_MAX.
+The closer a score gets to HISTORY_MAX (or -HISTORY_MAX), the smaller the change, so the scores always stay within the bounds.
+This also means that older results slowly fade away, and moves that stop being good lose their high scores again.
+
+Counter moves:
+Many good moves are a direct reply to the move the opponent just played (for example moving a piece away that was just attacked).
+For each previous move [side][piece][toSq], we store the quiet move that last caused a beta cutoff in reply to it.
+The counter move gets a big bonus when ordering the quiet moves.
+
+Continuation history:
+A history table that also depends on the previous moves: [side][previous piece][previous toSq][piece][toSq].
+We keep one table for the move 1 ply back (the opponent's move) and one table for the move 2 plies back (our own previous move).
+This catches moves that are only good in combination with a certain previous move, for example following up on our own plan.
+The continuation history is updated at the same time as the normal history, with the same bonus.
+
+When ordering the quiet moves, the history, both continuation histories and the counter move bonus are added together.
+
+Capture history:
+The threat moves are mainly ordered by the material they win (see search_ordering.go), which doesn't learn anything during the search.
+The capture history [side][piece][toSq][captured piece] stores how often a capture caused a beta cutoff at normal nodes.
+It is updated in the same way as the normal history (with gravity), and is used:
+- As a tiebreaker between good threat moves with the same material score.
+- To order the bad threat moves, because captures that lose material according to SEE but still often cause cutoffs are worth trying first.
+Promotions without a capture use CAPTURE_HISTORY_NO_CAPTURE as the captured piece.
 */
 
+const (
+	HISTORY_MAX                int = 16384 // history scores stay between -HISTORY_MAX and HISTORY_MAX
+	HISTORY_BONUS_MAX          int = 1600  // maximum bonus (or penalty) for a single update
+	HISTORY_COUNTER_MOVE_BONUS int = 8192  // ordering bonus for the counter move of the previous move
+	CONTINUATION_HISTORY_PLIES int = 2     // number of previous moves with a continuation history (1 and 2 plies back)
+	CAPTURE_HISTORY_NO_CAPTURE int = 6     // captured piece index in the capture history for promotions without a capture
+)
+
 type HistoryTable struct {
-	entries [2][6][64]int
+	entries      [2][6][64]int                                      // [side][piece][toSq]
+	counterMoves [2][6][64]Move                                     // [side][previous piece][previous toSq]
+	continuation [CONTINUATION_HISTORY_PLIES][2][6][64][6][64]int32 // [plies back - 1][side][previous piece][previous toSq][piece][toSq]
+	captures     [2][6][64][7]int                                   // [side][piece][toSq][captured piece]
 }
 
 func getNewHistoryTable() *HistoryTable {
 	newHistoryTable := HistoryTable{}
+	for side := 0; side < 2; side++ {
+		for piece := 0; piece < 6; piece++ {
+			for sq := 0; sq < 64; sq++ {
+				newHistoryTable.counterMoves[side][piece][sq] = BLANK_MOVE
+			}
+		}
+	}
 	return &newHistoryTable
 }
 
-// give a big bonus for moves causing a beta cutoff
-func (h *HistoryTable) goodBetaMove(move Move, currentDepth int, side int) {
-	h.entries[side][move.getPiece()][move.getToSq()] += currentDepth * currentDepth
+// returns the score updated with the bonus (or penalty if negative), using the gravity formula to keep it within the bounds
+func getHistoryGravityScore(score int, bonus int) int {
+	if bonus > HISTORY_BONUS_MAX {
+		bonus = HISTORY_BONUS_MAX
+	} else if bonus < 0-HISTORY_BONUS_MAX {
+		bonus = 0 - HISTORY_BONUS_MAX
+	}
+
+	absBonus := bonus
+	if absBonus < 0 {
+		absBonus = 0 - absBonus
+	}
+	return score + bonus - (score*absBonus)/HISTORY_MAX
+}
+
+// updates the history and the continuation histories of the move with the bonus (or penalty if negative)
+// previousMoves are the moves 1 and 2 plies back (blank moves are skipped)
+func (h *HistoryTable) updateMove(move Move, bonus int, side int, previousMoves [CONTINUATION_HISTORY_PLIES]Move) {
+	piece := move.getPiece()
+	toSq := move.getToSq()
+
+	h.entries[side][piece][toSq] = getHistoryGravityScore(h.entries[side][piece][toSq], bonus)
+
+	for i, previousMove := range previousMoves {
+		if previousMove == BLANK_MOVE {
+			continue
+		}
+		entry := &h.continuation[i][side][previousMove.getPiece()][previousMove.getToSq()][piece][toSq]
+		*entry = int32(getHistoryGravityScore(int(*entry), bonus))
+	}
+}
+
+// give a big bonus for moves causing a beta cutoff, and store the move as the counter move of the previous move
+func (h *HistoryTable) goodBetaMove(move Move, currentDepth int, side int, previousMoves [CONTINUATION_HISTORY_PLIES]Move) {
+	h.updateMove(move, currentDepth*currentDepth, side, previousMoves)
+
+	if previousMoves[0] != BLANK_MOVE {
+		h.counterMoves[side][previousMoves[0].getPiece()][previousMoves[0].getToSq()] = move
+	}
 }
 
 // give a small bonus for moves causing an alpha improvement
-func (h *HistoryTable) goodAlphaMove(move Move, currentDepth int, side int) {
-	h.entries[side][move.getPiece()][move.getToSq()] += currentDepth
+func (h *HistoryTable) goodAlphaMove(move Move, currentDepth int, side int, previousMoves [CONTINUATION_HISTORY_PLIES]Move) {
+	h.updateMove(move, currentDepth, side, previousMoves)
 }
 
 // reduce the score of moves searched that did not give an alpha improvement
-// we have a floor of 0 on the score
-func (h *HistoryTable) badAlphaMove(move Move, currentDepth int, side int) {
-	previousValue := h.entries[side][move.getPiece()][move.getToSq()]
-	if previousValue > currentDepth {
-		h.entries[side][move.getPiece()][move.getToSq()] -= currentDepth
-	} else {
-		h.entries[side][move.getPiece()][move.getToSq()] = 0
+func (h *HistoryTable) badAlphaMove(move Move, currentDepth int, side int, previousMoves [CONTINUATION_HISTORY_PLIES]Move) {
+	h.updateMove(move, 0-currentDepth, side, previousMoves)
+}
+
+// give a big bonus for threat moves causing a beta cutoff
+func (h *HistoryTable) goodBetaCapture(move Move, capturedPiece int, currentDepth int, side int) {
+	entry := &h.captures[side][move.getPiece()][move.getToSq()][capturedPiece]
+	*entry = getHistoryGravityScore(*entry, currentDepth*currentDepth)
+}
+
+// reduce the score of threat moves searched that did not give an alpha improvement
+func (h *HistoryTable) badAlphaCapture(move Move, capturedPiece int, currentDepth int, side int) {
+	entry := &h.captures[side][move.getPiece()][move.getToSq()][capturedPiece]
+	*entry = getHistoryGravityScore(*entry, 0-currentDepth)
+}
+
+// returns the capture history score of a threat move
+func (h *HistoryTable) getCaptureScore(move Move, capturedPiece int, side int) int {
+	return h.captures[side][move.getPiece()][move.getToSq()][capturedPiece]
+}
+
+// returns the piece captured by the threat move, to look up the capture history
+// returns CAPTURE_HISTORY_NO_CAPTURE for promotions without a capture
+func (pos *Position) getCapturedPiece(move Move) int {
+	switch move.getMoveType() {
+	case MOVE_TYPE_CAPTURE:
+		enSide := SIDE_WHITE
+		if pos.isWhiteTurn {
+			enSide = SIDE_BLACK
+		}
+		return pos.getPieceOnSq(enSide, move.getToSq())
+	case MOVE_TYPE_EN_PASSANT:
+		return PIECE_PAWN
+	}
+	return CAPTURE_HISTORY_NO_CAPTURE
+}
+
+// returns the counter move for the previous move (a blank move if there is none)
+func (h *HistoryTable) getCounterMove(side int, previousMove Move) Move {
+	if previousMove == BLANK_MOVE {
+		return BLANK_MOVE
+	}
+	return h.counterMoves[side][previousMove.getPiece()][previousMove.getToSq()]
+}
+
+// returns the sum of the continuation history scores of the move for the previous moves
+func (h *HistoryTable) getContinuationScore(move Move, side int, previousMoves [CONTINUATION_HISTORY_PLIES]Move) int {
+	score := 0
+	for i, previousMove := range previousMoves {
+		if previousMove == BLANK_MOVE {
+			continue
+		}
+		score += int(h.continuation[i][side][previousMove.getPiece()][previousMove.getToSq()][move.getPiece()][move.getToSq()])
+	}
+	return score
+}
+
+// returns the moves played 1 and 2 plies before the current position
+// a move is blank if there is no such move, or if it was a null move
+func (pos *Position) getPreviousMoves() [CONTINUATION_HISTORY_PLIES]Move {
+	var previousMoves [CONTINUATION_HISTORY_PLIES]Move
+	for i := range previousMoves {
+		index := pos.previousGameStatesCounter - 1 - i
+		if index >= 0 {
+			previousMoves[i] = pos.previousGameStates[index].move
+		} else {
+			previousMoves[i] = BLANK_MOVE
+		}
 	}
+	return previousMoves
 }
 
-// returns a slice of other quiet moves ordered from best to worst based on history scores
-func (pos *Position) orderQuietHistoryMoves(moves []Move, historyTable *HistoryTable) {
+// orders the other quiet moves from best to worst based on history scores
+// the score of a move is the history, plus both continuation histories, plus a bonus if it is the counter move of the previous move
+// returns whether the counter move was found in the moves
+func (pos *Position) orderQuietHistoryMoves(moves []Move, historyTable *HistoryTable, previousMoves [CONTINUATION_HISTORY_PLIES]Move) bool {
 
 	// get the side
 	side := SIDE_BLACK
@@ -61,6 +205,10 @@ func (pos *Position) orderQuietHistoryMoves(moves []Move, historyTable *HistoryT
 		side = SIDE_WHITE
 	}
 
+	// get the counter move of the previous move
+	counterMove := historyTable.getCounterMove(side, previousMoves[0])
+	counterMoveFound := false
+
 	// loop over moves to score them
 	for i, move := range moves {
 
@@ -68,11 +216,15 @@ func (pos *Position) orderQuietHistoryMoves(moves []Move, historyTable *HistoryT
 		piece := move.getPiece()
 		toSq := move.getToSq()
 
-		// we only update the score if the history score is > 0
-		historyScore := historyTable.entries[side][piece][toSq]
-		if historyScore > 0 {
-			moves[i].setMoveOrderingScore(historyScore)
+		// the history scores can be negative, so we add an offset to keep the score positive
+		historyScore := (1+CONTINUATION_HISTORY_PLIES)*HISTORY_MAX + historyTable.entries[side][piece][toSq]
+		historyScore += historyTable.getContinuationScore(move, side, previousMoves)
+
+		if move == counterMove {
+			historyScore += HISTORY_COUNTER_MOVE_BONUS
+			counterMoveFound = true
 		}
+		moves[i].setMoveOrderingScore(historyScore)
 	}
 
 	// now sort the moves
@@ -84,4 +236,6 @@ func (pos *Position) orderQuietHistoryMoves(moves []Move, historyTable *HistoryT
 	for i := range moves {
 		moves[i].clearMoveOrderingScore()
 	}
+
+	return counterMoveFound
 }
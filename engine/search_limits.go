@@ -0,0 +1,122 @@
+package main
+
+import (
+	"time"
+)
+
+// --------------------------------------------------------------------------------------------------------------------
+// ---------------------------------------------------- Search Limits -------------------------------------------------
+// --------------------------------------------------------------------------------------------------------------------
+/*
+A search ends when any of its limits is reached:
+- Time: the allowed time in ms (checked every NODES_BEFORE_CHECK_INTERRUPT nodes in negamax).
+- Depth: the maximum iterative deepening depth (checked after each completed iteration).
+- Nodes: the maximum number of nodes searched (checked at every node in negamax).
+- Mate: the search ends once a mate for the side to move is found within the given number of moves.
+
+The search can also be stopped from outside at any time (see search_control.go).
+
+The moves searched at the root can also be restricted to a list of moves ("go searchmoves").
+The best move, the pv and the info lines then only come from those moves.
+
+A ponder search ("go ponder") has no time limit while pondering.
+Once "ponderhit" is received, the search continues as a normal search with the ponder hit time limit,
+counted from the moment the ponder hit was received, and keeping all the work done while pondering.
+
+When the time, depth or node limit is reached in the middle of an iteration, that iteration is thrown away,
+and the best move from the last completed iteration is used.
+*/
+
+const (
+	MIN_SEARCH_DEPTH int = 2 // the first iteration, unless the depth limit is lower (any completed iteration gives a best move)
+)
+
+type SearchLimits struct {
+	timeMs      int    // allowed time for the search in ms
+	maxDepth    int    // maximum depth for iterative deepening
+	maxNodes    int    // maximum nodes for the search, 0 if there is no node limit
+	mateInMoves int    // stop the search when a mate in this many moves (or less) is found, 0 if we don't search for a mate
+	searchMoves []Move // only search these moves at the root, all moves are searched if empty
+
+	ponder          bool // search without a time limit until "ponderhit" or "stop" is received
+	ponderHitTimeMs int  // allowed time for the search in ms after "ponderhit" is received
+}
+
+// returns search limits with only a time limit
+// the other limits are set to the engine maximums
+func getNewSearchLimits(timeMs int) SearchLimits {
+	return SearchLimits{
+		timeMs:      timeMs,
+		maxDepth:    MAX_DEPTH,
+		maxNodes:    0,
+		mateInMoves: 0,
+		searchMoves: nil,
+
+		ponder:          false,
+		ponderHitTimeMs: 0,
+	}
+}
+
+// sets the maximum depth, within the depths the engine can search
+func (limits *SearchLimits) setMaxDepth(depth int) {
+	if depth < 1 {
+		depth = 1
+	}
+	if depth > MAX_DEPTH {
+		depth = MAX_DEPTH
+	}
+	limits.maxDepth = depth
+}
+
+// returns true if the iteration score is a mate for the side to move that satisfies the mate limit
+func (limits *SearchLimits) isMateLimitReached(score int, rootPly int) bool {
+	if limits.mateInMoves <= 0 {
+		return false
+	}
+	movesToMate, isMate := getMovesToMate(score, rootPly)
+	return isMate && movesToMate > 0 && movesToMate <= limits.mateInMoves
+}
+
+// turns a ponder search into a normal search after "ponderhit" was received
+// the time limit starts from now, because our clock only starts running once the opponent played the expected move
+func (pos *Position) convertPonderToNormalSearch() {
+	pos.searchPondering = false
+	pos.timeStartingTime = time.Now()
+	pos.timeTotalAllowedTime = pos.timePonderHitAllowedTime
+}
+
+// returns true if the root moves are restricted to a list of moves, or some root moves are excluded (multipv)
+func (pos *Position) isRootRestricted() bool {
+	return len(pos.searchRootMoves) > 0 || len(pos.searchExcludedRootMoves) > 0
+}
+
+// removes all the moves that are not in the list of allowed root moves, and all the excluded root moves
+// the moves are filtered in place, keeping the order of the remaining moves
+func (pos *Position) filterRootMoves(moves []Move) []Move {
+	filteredMoves := moves[:0]
+	for _, move := range moves {
+		if pos.isAllowedRootMove(move) {
+			filteredMoves = append(filteredMoves, move)
+		}
+	}
+	return filteredMoves
+}
+
+// returns true if the move can be searched at the root
+func (pos *Position) isAllowedRootMove(move Move) bool {
+	for _, excludedMove := range pos.searchExcludedRootMoves {
+		if move == excludedMove {
+			return false
+		}
+	}
+
+	if len(pos.searchRootMoves) == 0 {
+		return true
+	}
+	for _, allowedMove := range pos.searchRootMoves {
+		if move == allowedMove {
+			return true
+		}
+	}
+	return false
+}
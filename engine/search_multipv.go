@@ -0,0 +1,84 @@
+package main
+
+import (
+	"sort"
+)
+
+// --------------------------------------------------------------------------------------------------------------------
+// ------------------------------------------------------- MultiPV ----------------------------------------------------
+// --------------------------------------------------------------------------------------------------------------------
+/*
+With the "MultiPV" uci option, the engine searches and reports the best N lines instead of only the best line.
+This is used for analysis, for example to compare the top moves in an opening position.
+
+Each iteration of iterative deepening does N passes of the root search at the same depth:
+- The first pass searches all root moves and finds the best line.
+- Each next pass excludes the root moves already found in this iteration, and finds the next best line.
+- The passes stop early if there are no root moves left.
+The lines are then sorted by score, and each line is sent to the GUI as "info multipv k ...".
+
+When the root moves are excluded, the root score is not the score of the position, so we don't store the root in the TT.
+Each pass follows the same line from the previous iteration (the k-th pass follows the k-th line).
+If an iteration is terminated during any of the passes, all the lines of that iteration are thrown away.
+
+With MultiPV set to 1 (the default), there is only one pass and the search is the same as a normal search.
+*/
+
+type MultiPVLine struct {
+	move  Move   // the root move of the line
+	score int    // the score of the line from the point of view of the side to move at the root
+	pv    []Move // the principal variation of the line, starting with the root move
+}
+
+// searches the best lines at the given depth, one root search for each line
+// returns the lines sorted from best to worst, and a flag for whether the search was terminated
+func (pos *Position) searchMultiPVLines(depth int, qsDepth int, tt *TranspositionTable, ht *HistoryTable) ([]MultiPVLine, bool) {
+
+	var lines []MultiPVLine
+	pos.searchExcludedRootMoves = nil
+
+	for pvIndex := 0; pvIndex < pos.searchMultiPV; pvIndex++ {
+
+		// follow the line with the same index from the previous iteration
+		pos.pvFollowLine = nil
+		if pvIndex < len(pos.multiPVLines) {
+			pos.pvFollowLine = pos.multiPVLines[pvIndex].pv
+		}
+
+		// do the search with an aspiration window around the score of the same line in the previous iteration
+		// this also resets the best move of the pass (see search_aspiration.go)
+		score, terminated := pos.searchRootWithAspiration(depth, qsDepth, pvIndex, tt, ht)
+		if terminated {
+			pos.searchExcludedRootMoves = nil
+			return nil, true
+		}
+
+		// if all the root moves were already excluded, there are no lines left
+		// the first pass always gives a line, also if there are no legal moves (to report the checkmate or draw score)
+		if pvIndex > 0 && pos.bestMoveSoFar == BLANK_MOVE {
+			break
+		}
+
+		lines = append(lines, MultiPVLine{
+			move:  pos.bestMoveSoFar,
+			score: score,
+			pv:    pos.getRootPVLine(),
+		})
+
+		if pos.bestMoveSoFar == BLANK_MOVE {
+			break
+		}
+
+		// exclude the root move of this line for the next passes
+		pos.searchExcludedRootMoves = append(pos.searchExcludedRootMoves, pos.bestMoveSoFar)
+	}
+
+	pos.searchExcludedRootMoves = nil
+	pos.bestMoveSoFar = BLANK_MOVE
+
+	// sort the lines from best to worst
+	// later passes can score higher than earlier passes because of search instability, the GUI expects the best line first
+	sort.SliceStable(lines, func(i, j int) bool { return lines[i].score > lines[j].score })
+
+	return lines, false
+}
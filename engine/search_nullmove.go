@@ -23,6 +23,7 @@ func (pos *Position) makeNullMove() {
 	pos.previousGameStates[pos.previousGameStatesCounter].evalMidVsEndStage = pos.evalMidVsEndStage
 	pos.previousGameStates[pos.previousGameStatesCounter].evalWhiteMobility = pos.evalWhiteMobility
 	pos.previousGameStates[pos.previousGameStatesCounter].evalBlackMobility = pos.evalBlackMobility
+	pos.previousGameStates[pos.previousGameStatesCounter].move = BLANK_MOVE
 
 	pos.previousGameStatesCounter += 1
 
@@ -13,91 +13,95 @@ const (
 	// because we encode the score into the move, so having negative numbers will mess that up
 	// the max we can gain or lose from a move is 900 + 800 = 1700 (promote a pawn to a queen while capturing a queen)
 	MOVE_ORDERING_SCORE_OFFSET int = 4096
+
+	// the capture history is bounded by HISTORY_MAX, so the material score times this scale
+	// leaves room for the capture history score (plus HISTORY_MAX to keep it positive) as a tiebreaker
+	CAPTURE_HISTORY_ORDERING_SCALE int = 2*HISTORY_MAX + 1
 )
 
-// returns a slice of threat moves ordered from best to worst at qs nodes
+// returns the good and bad threat moves at qs nodes, each ordered from best to worst
+// good threat moves don't lose material according to SEE, bad threat moves do (these are pruned in qs when not in check)
 func (pos *Position) getOrderedThreatMovesQsNodes() ([]Move, []Move) {
 
 	// create a copy of the available moves
 	moves := make([]Move, pos.threatMovesCounter)
 	copy(moves, pos.threatMoves[:pos.threatMovesCounter])
 
-	// loop over moves to score them
-	for i, move := range moves {
+	// score the moves, and split them into good and bad threat moves
+	goodMoves, badMoves := pos.scoreAndSplitThreatMoves(moves)
 
-		// set the score of the move to zero
-		moveOrderScore := MOVE_ORDERING_SCORE_OFFSET
+	// now sort the moves
+	// define the custom comparator function
+	// sort the moves based on the scores using the comparator function
+	sort.Slice(goodMoves, func(i, j int) bool { return goodMoves[i].getMoveOrderingScore() > goodMoves[j].getMoveOrderingScore() })
+	sort.Slice(badMoves, func(i, j int) bool { return badMoves[i].getMoveOrderingScore() > badMoves[j].getMoveOrderingScore() })
 
-		// get the relevant move information
-		moveType := move.getMoveType()
-		promotionType := move.getPromotionType()
+	// finally clear the move ordering scores (to make comparison easier later to other moves)
+	clearMoveOrderingScores(goodMoves)
+	clearMoveOrderingScores(badMoves)
 
-		// ------------------------------------------- PROMOTIONS: VALUE GAINED -------------------------------------
-		// if there is a promotion, add that promoted piece's value less the pawn value
-		// the score is done from the white side (positve score), because for move ordering this is the point
-		if promotionType != PROMOTION_NONE {
-			moveOrderScore += (evalTableMaterial[SIDE_WHITE][promotionType] - VALUE_PAWN)
-		}
+	return goodMoves, badMoves
+}
 
-		// ------------------------------------------- CAPTURES: VALUE GAINED -------------------------------------
-		// MOVE_TYPE_EN_PASSANT: no bonus because pawn traded for pawn = 0 incremental value
-		// MOVE_TYPE_CAPTURE: evaluate below
-		if moveType == MOVE_TYPE_CAPTURE {
-			var enemyPiece int = 6 // set to 6 to catch bugs
-			var enSide int = SIDE_WHITE
-			if pos.isWhiteTurn {
-				enSide = SIDE_BLACK
-			}
-			toSq := move.getToSq()
-			piece := move.getPiece()
+// returns the good and bad threat moves at normal nodes, each ordered from best to worst
+// good threat moves don't lose material according to SEE, bad threat moves do (these are searched after the killer moves)
+// the capture history is used as a tiebreaker for the good threat moves, and to order the bad threat moves (see search_history.go)
+func (pos *Position) getOrderedThreatMovesNormalNodes(historyTable *HistoryTable) ([]Move, []Move) {
 
-			if pos.pieces[enSide][PIECE_PAWN].isBitSet(toSq) {
-				enemyPiece = PIECE_PAWN
-			} else if pos.pieces[enSide][PIECE_KNIGHT].isBitSet(toSq) {
-				enemyPiece = PIECE_KNIGHT
-			} else if pos.pieces[enSide][PIECE_BISHOP].isBitSet(toSq) {
-				enemyPiece = PIECE_BISHOP
-			} else if pos.pieces[enSide][PIECE_ROOK].isBitSet(toSq) {
-				enemyPiece = PIECE_ROOK
-			} else if pos.pieces[enSide][PIECE_QUEEN].isBitSet(toSq) {
-				enemyPiece = PIECE_QUEEN
-			}
+	// create a copy of the available moves
+	moves := make([]Move, pos.threatMovesCounter)
+	copy(moves, pos.threatMoves[:pos.threatMovesCounter])
 
-			// add the difference between the captured and friendly piece
-			// therefore lower piece value captures higher piece value is evaluated first
-			moveOrderScore += (evalTableMaterial[SIDE_WHITE][enemyPiece]) - (evalTableMaterial[SIDE_WHITE][piece])
-		}
+	// score the moves, and split them into good and bad threat moves
+	goodMoves, badMoves := pos.scoreAndSplitThreatMoves(moves)
 
-		// ------------------------------------------- SAVE THE SCORE -------------------------------------
-		// finally set the updated move score if the score is not zero
-		moves[i].setMoveOrderingScore(moveOrderScore)
+	// get the side
+	side := SIDE_BLACK
+	if pos.isWhiteTurn {
+		side = SIDE_WHITE
+	}
+
+	// ------------------------------------------- CAPTURE HISTORY -------------------------------------
+	// good threat moves: the material score comes first, the capture history breaks ties
+	for i, move := range goodMoves {
+		materialScore := move.getMoveOrderingScore()
+		captureScore := HISTORY_MAX + historyTable.getCaptureScore(move, pos.getCapturedPiece(move), side)
+
+		goodMoves[i].clearMoveOrderingScore()
+		goodMoves[i].setMoveOrderingScore(materialScore*CAPTURE_HISTORY_ORDERING_SCALE + captureScore)
+	}
+
+	// bad threat moves: the capture history comes first, the material lost (SEE) breaks ties
+	for i, move := range badMoves {
+		seeScore := move.getMoveOrderingScore()
+		captureScore := HISTORY_MAX + historyTable.getCaptureScore(move, pos.getCapturedPiece(move), side)
+
+		badMoves[i].clearMoveOrderingScore()
+		badMoves[i].setMoveOrderingScore(captureScore*CAPTURE_HISTORY_ORDERING_SCALE + seeScore)
 	}
 
 	// now sort the moves
 	// define the custom comparator function
 	// sort the moves based on the scores using the comparator function
-	sort.Slice(moves, func(i, j int) bool { return moves[i].getMoveOrderingScore() > moves[j].getMoveOrderingScore() })
+	sort.Slice(goodMoves, func(i, j int) bool { return goodMoves[i].getMoveOrderingScore() > goodMoves[j].getMoveOrderingScore() })
+	sort.Slice(badMoves, func(i, j int) bool { return badMoves[i].getMoveOrderingScore() > badMoves[j].getMoveOrderingScore() })
 
 	// finally clear the move ordering scores (to make comparison easier later to other moves)
-	for i := range moves {
-		moves[i].clearMoveOrderingScore()
-	}
+	clearMoveOrderingScores(goodMoves)
+	clearMoveOrderingScores(badMoves)
 
-	return moves, []Move{}
+	return goodMoves, badMoves
 }
 
-// returns a slice of threat moves ordered from best to worst at normal nodes
-func (pos *Position) getOrderedThreatMovesNormalNodes() ([]Move, []Move) {
-
-	// create a copy of the available moves
-	moves := make([]Move, pos.threatMovesCounter)
-	copy(moves, pos.threatMoves[:pos.threatMovesCounter])
+// scores the threat moves, and splits them into good threat moves and bad threat moves
+// the moves are split in place: the good moves are at the start of the slice, the bad moves at the end
+func (pos *Position) scoreAndSplitThreatMoves(moves []Move) ([]Move, []Move) {
 
-	// loop over moves to score them
-	for i, move := range pos.threatMoves[:pos.threatMovesCounter] {
+	goodMovesCount := 0
+	for i, move := range moves {
 
 		// set the score of the move to zero
-		moveOrderScore := MOVE_ORDERING_SCORE_OFFSET
+		moveOrderScore := 0
 
 		// get the relevant move information
 		moveType := move.getMoveType()
@@ -114,61 +118,46 @@ func (pos *Position) getOrderedThreatMovesNormalNodes() ([]Move, []Move) {
 		// MOVE_TYPE_EN_PASSANT: no bonus because pawn traded for pawn = 0 incremental value
 		// MOVE_TYPE_CAPTURE: evaluate below
 		if moveType == MOVE_TYPE_CAPTURE {
-			var enemyPiece int = 6 // set to 6 to catch bugs
 			var enSide int = SIDE_WHITE
 			if pos.isWhiteTurn {
 				enSide = SIDE_BLACK
 			}
-			toSq := move.getToSq()
+			enemyPiece := pos.getPieceOnSq(enSide, move.getToSq())
 			piece := move.getPiece()
 
-			if pos.pieces[enSide][PIECE_PAWN].isBitSet(toSq) {
-				enemyPiece = PIECE_PAWN
-			} else if pos.pieces[enSide][PIECE_KNIGHT].isBitSet(toSq) {
-				enemyPiece = PIECE_KNIGHT
-			} else if pos.pieces[enSide][PIECE_BISHOP].isBitSet(toSq) {
-				enemyPiece = PIECE_BISHOP
-			} else if pos.pieces[enSide][PIECE_ROOK].isBitSet(toSq) {
-				enemyPiece = PIECE_ROOK
-			} else if pos.pieces[enSide][PIECE_QUEEN].isBitSet(toSq) {
-				enemyPiece = PIECE_QUEEN
-			}
-
 			// add the difference between the captured and friendly piece
 			// therefore lower piece value captures higher piece value is evaluated first
 			moveOrderScore += (evalTableMaterial[SIDE_WHITE][enemyPiece]) - (evalTableMaterial[SIDE_WHITE][piece])
 		}
 
-		// ------------------------------------------- SAVE THE SCORE -------------------------------------
-		// finally set the updated move score if the score is not zero
-		moves[i].setMoveOrderingScore(moveOrderScore)
-	}
-
-	// now sort the moves
-	// define the custom comparator function
-	// sort the moves based on the scores using the comparator function
-	sort.Slice(moves, func(i, j int) bool { return moves[i].getMoveOrderingScore() > moves[j].getMoveOrderingScore() })
+		// ------------------------------------------- SEE: GOOD OR BAD THREAT -------------------------------------
+		// if the captured piece is worth at least as much as the capturing piece, the move can never lose material
+		// otherwise (for example a queen capturing a pawn), we use SEE to check whether the move actually loses material
+		// good moves that needed SEE are scored by the material they win, bad moves are scored by the material they lose
+		isGoodMove := true
+		if moveOrderScore < 0 {
+			seeValue := pos.getSEE(move)
+			isGoodMove = seeValue >= 0
+			moveOrderScore = seeValue
+		}
 
-	// finally clear the move ordering scores (to make comparison easier later to other moves)
-	// we also catch the index of the moves where the "bad" captures start, to search them later
-	badCapturesStartIndex := -1 // set to -1 to catch bugs
-	for i, move := range moves {
+		// ------------------------------------------- SAVE THE SCORE -------------------------------------
+		// finally set the updated move score (the offset keeps the score positive)
+		moves[i].setMoveOrderingScore(MOVE_ORDERING_SCORE_OFFSET + moveOrderScore)
 
-		// if we have not yet got the index of bad captures, get that
-		if badCapturesStartIndex == -1 {
-			if move.getMoveOrderingScore() < MOVE_ORDERING_SCORE_OFFSET {
-				badCapturesStartIndex = i
-			}
+		// move the good moves to the start of the slice
+		if isGoodMove {
+			moves[goodMovesCount], moves[i] = moves[i], moves[goodMovesCount]
+			goodMovesCount++
 		}
-
-		// finally clear the move ordering score
-		moves[i].clearMoveOrderingScore()
 	}
 
-	// split the moves into good and bad captures
-	if badCapturesStartIndex == -1 {
-		return moves, []Move{}
-	} else {
-		return moves[:badCapturesStartIndex], moves[badCapturesStartIndex:]
+	return moves[:goodMovesCount], moves[goodMovesCount:]
+}
+
+// clears the move ordering scores of the moves
+func clearMoveOrderingScores(moves []Move) {
+	for i := range moves {
+		moves[i].clearMoveOrderingScore()
 	}
 }
@@ -0,0 +1,37 @@
+package main
+
+// --------------------------------------------------------------------------------------------------------------------
+// ------------------------------------------------------ ProbCut -----------------------------------------------------
+// --------------------------------------------------------------------------------------------------------------------
+/*
+At high depths, a node where a capture wins material very often fails high, but proving that with a full depth search is expensive.
+ProbCut tries to prove the cutoff cheaply:
+- We raise beta by a margin (the ProbCut beta).
+- We try the good captures (captures that don't lose material according to SEE), in the threat move ordering.
+- Each capture is searched with a shallow null window search against the ProbCut beta.
+- If a capture reaches the ProbCut beta at the shallow depth, we trust that the full depth search would reach beta,
+  and we prune the node by returning beta.
+
+The margin makes the shallow result reliable: a move that beats beta by a margin at a low depth
+will very likely still beat beta at the full depth.
+
+ProbCut is only done at nodes with a null window (not on the principal variation), not at the root,
+not in exclusion searches (see search_singular.go), when we are not in check, and when beta is not a checkmate score.
+We also skip ProbCut when the TT already tells us that the node will not reach the ProbCut beta.
+*/
+
+const (
+	PROBCUT_MIN_DEPTH       int = 5   // minimum depth of a node to try ProbCut
+	PROBCUT_DEPTH_REDUCTION int = 3   // the captures are searched with this reduction, on top of the normal 1 ply for making the move
+	PROBCUT_MARGIN          int = 200 // the ProbCut beta is beta plus this margin
+)
+
+// returns the raised beta used by the shallow ProbCut searches
+func getProbCutBeta(beta int) int {
+	return beta + PROBCUT_MARGIN
+}
+
+// returns the reduced depth of the ProbCut searches after making a capture at the depth
+func getProbCutSearchDepth(currentDepth int) int {
+	return currentDepth - 1 - PROBCUT_DEPTH_REDUCTION
+}
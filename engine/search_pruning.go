@@ -0,0 +1,145 @@
+package main
+
+// --------------------------------------------------------------------------------------------------------------------
+// ----------------------------------------- Delta, Futility and Late Move Pruning ------------------------------------
+// --------------------------------------------------------------------------------------------------------------------
+/*
+These prunings skip moves that are very unlikely to raise alpha, close to the leaves of the tree.
+They all compare the static evaluation of the node (or the number of moves already searched) to alpha,
+so they are only used when alpha is not a checkmate score, and never when the side to move is in check.
+
+Delta pruning (quiescence nodes):
+In quiescence, the side to move can always stand pat, so alpha is at least the static evaluation.
+If the stand pat score plus the best case gain of a capture (the captured piece, plus the promotion gain),
+plus a safety margin, still can't reach alpha, the capture can't raise alpha and we skip it.
+
+Frontier futility pruning (depth 1 and 2):
+If the static evaluation plus a margin for the depth is still at or below alpha,
+quiet moves are very unlikely to raise alpha before the search reaches quiescence.
+We then skip the quiet moves (after at least one move was searched), except quiet moves that give check.
+
+Late move pruning (LMP) (low depths):
+With good move ordering, the quiet moves at the end of the move list very rarely cause a cutoff at low depths.
+After a certain number of moves were searched at the node (growing with the depth), we skip the remaining quiet moves,
+except quiet moves that give check.
+
+Futility pruning and LMP are only done at nodes with a null window (not on the principal variation), and not at the root.
+
+All the margins and move counts below can be tuned, the number of pruned moves is logged to validate the node reductions.
+*/
+
+const (
+	DELTA_PRUNING_MARGIN int = 200 // safety margin added to the best case gain of a capture in quiescence
+
+	FUTILITY_MAX_DEPTH         int = 2   // maximum depth where we do futility pruning
+	FUTILITY_MARGIN_BASE       int = 100 // futility margin at depth 0 (the margin at a depth is the base plus the depth times the margin per depth)
+	FUTILITY_MARGIN_PER_DEPTH  int = 150 // increase of the futility margin for each depth
+	LMP_MAX_DEPTH              int = 3   // maximum depth where we do late move pruning
+	LMP_MOVES_BASE             int = 3   // moves searched before late move pruning at depth 0
+	LMP_MOVES_PER_DEPTH_SQUARE int = 2   // increase of the moves searched before late move pruning, per depth squared
+)
+
+// returns the futility margin for the depth
+func getFutilityMargin(depth int) int {
+	return FUTILITY_MARGIN_BASE + depth*FUTILITY_MARGIN_PER_DEPTH
+}
+
+// returns the number of moves searched at a node before late move pruning starts at the depth
+func getLateMovePruningCount(depth int) int {
+	return LMP_MOVES_BASE + depth*depth*LMP_MOVES_PER_DEPTH_SQUARE
+}
+
+// returns the best case material gain of a threat move (the captured piece plus the promotion gain), used for delta pruning
+func (pos *Position) getThreatMoveMaxGain(move Move) int {
+	gain := 0
+
+	switch move.getMoveType() {
+	case MOVE_TYPE_CAPTURE:
+		enSide := SIDE_WHITE
+		if pos.isWhiteTurn {
+			enSide = SIDE_BLACK
+		}
+		gain += evalTableMaterial[SIDE_WHITE][pos.getPieceOnSq(enSide, move.getToSq())]
+	case MOVE_TYPE_EN_PASSANT:
+		gain += VALUE_PAWN
+	}
+
+	promotionType := move.getPromotionType()
+	if promotionType != PROMOTION_NONE {
+		gain += evalTableMaterial[SIDE_WHITE][promotionType] - VALUE_PAWN
+	}
+
+	return gain
+}
+
+// returns true if the move gives check to the enemy king (directly, or by uncovering a sliding piece)
+// castling moves are always treated as checking moves, so that they are never pruned
+func (pos *Position) isCheckingMove(move Move) bool {
+
+	if move.getMoveType() == MOVE_TYPE_CASTLE {
+		return true
+	}
+
+	side := SIDE_BLACK
+	enSide := SIDE_WHITE
+	if pos.isWhiteTurn {
+		side = SIDE_WHITE
+		enSide = SIDE_BLACK
+	}
+	enKingSq := pos.pieces[enSide][PIECE_KING].getLSBSq()
+
+	fromSq := move.getFromSq()
+	toSq := move.getToSq()
+	piece := move.getPiece()
+	if move.getPromotionType() != PROMOTION_NONE {
+		piece = move.getPromotionType()
+	}
+
+	// the occupied squares after the move
+	occupied := pos.piecesAll[SIDE_BOTH]
+	occupied.clearBit(fromSq)
+	occupied.setBit(toSq)
+	if move.getMoveType() == MOVE_TYPE_EN_PASSANT {
+		if side == SIDE_WHITE {
+			occupied.clearBit(toSq - 8)
+		} else {
+			occupied.clearBit(toSq + 8)
+		}
+	}
+
+	// ___ Direct Checks ___
+	switch piece {
+	case PIECE_PAWN:
+		if movePawnsAttackingKingMasks[enKingSq][side].isBitSet(toSq) {
+			return true
+		}
+	case PIECE_KNIGHT:
+		if getKnightMovesPseudo(toSq).isBitSet(enKingSq) {
+			return true
+		}
+	case PIECE_BISHOP:
+		if getBishopMovesPseudo(toSq, occupied).isBitSet(enKingSq) {
+			return true
+		}
+	case PIECE_ROOK:
+		if getRookMovesPseudo(toSq, occupied).isBitSet(enKingSq) {
+			return true
+		}
+	case PIECE_QUEEN:
+		if (getBishopMovesPseudo(toSq, occupied) | getRookMovesPseudo(toSq, occupied)).isBitSet(enKingSq) {
+			return true
+		}
+	}
+
+	// ___ Discovered Checks ___
+	// our sliding pieces (other than the moved piece) that attack the enemy king after the move
+	var fromBB Bitboard
+	fromBB.setBit(fromSq)
+
+	diagonalSliders := (pos.pieces[side][PIECE_BISHOP] | pos.pieces[side][PIECE_QUEEN]) &^ fromBB
+	if getBishopMovesPseudo(enKingSq, occupied)&diagonalSliders != 0 {
+		return true
+	}
+	straightSliders := (pos.pieces[side][PIECE_ROOK] | pos.pieces[side][PIECE_QUEEN]) &^ fromBB
+	return getRookMovesPseudo(enKingSq, occupied)&straightSliders != 0
+}
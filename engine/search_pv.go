@@ -0,0 +1,109 @@
+package main
+
+// --------------------------------------------------------------------------------------------------------------------
+// ------------------------------------------------ Principal Variation -----------------------------------------------
+// --------------------------------------------------------------------------------------------------------------------
+/*
+The principal variation (PV) is the line of best moves the engine expects to be played from the root.
+We collect it during the search using a triangular PV table:
+- The row of each ply stores the best line found from that ply onwards: pvTable[ply][ply], pvTable[ply][ply+1], ...
+- Each node starts with an empty line (pvLength[ply] = ply).
+- When a move improves alpha, the line of the node becomes that move, followed by the line of the child node.
+
+After each completed iteration, the root line is stored (see search_multipv.go), and the best line is also stored in pvLine.
+The lines are:
+- Sent to the GUI in the "info ... pv" lines.
+- Used to find the move we expect the opponent to reply with (the "ponder" move of "bestmove").
+- Searched first in the next iteration: each node on the previous PV tries its PV move first.
+
+The table is indexed by ply, where the root is ply 1 (see negamax).
+Lines can be shorter than the search depth, for example when a node along the line returned a TT cutoff.
+*/
+
+// clears the line of the node at the given ply (done at the start of each node)
+func (pos *Position) resetPV(ply int) {
+	pos.pvLength[ply] = ply
+}
+
+// stores the move that improved alpha as the start of the line at the given ply,
+// followed by the line of the child node that was searched after making the move
+func (pos *Position) updatePV(move Move, ply int) {
+	pos.pvTable[ply][ply] = move
+
+	childLength := pos.pvLength[ply+1]
+	copy(pos.pvTable[ply][ply+1:childLength], pos.pvTable[ply+1][ply+1:childLength])
+
+	pos.pvLength[ply] = childLength
+}
+
+// returns a copy of the line found at the root after a completed root search
+func (pos *Position) getRootPVLine() []Move {
+	line := make([]Move, pos.pvLength[1]-1)
+	copy(line, pos.pvTable[1][1:pos.pvLength[1]])
+	return line
+}
+
+// returns the pv move of the node at the given ply if the node is still on the principal variation of the previous iteration
+// only the first node searched at each ply can be on the previous PV, so following stops for all the other nodes
+func (pos *Position) getPVMoveToFollow(ply int) Move {
+	pvMove := BLANK_MOVE
+	if pos.pvFollow && ply-1 < len(pos.pvFollowLine) {
+		pvMove = pos.pvFollowLine[ply-1]
+	}
+	pos.pvFollow = false
+	return pvMove
+}
+
+// returns the line as a string of uci moves, for example "e2e4 e7e5 g1f3"
+func getPVString(line []Move) string {
+	output := ""
+	for i, move := range line {
+		if i > 0 {
+			output += " "
+		}
+		output += getUCIMoveString(move)
+	}
+	return output
+}
+
+// returns the move we expect the opponent to play after the best move
+// this is normally the second move of the pv
+// if the pv is cut short after the best move (for example by a TT cutoff), we try the hash move of the position after the best move instead
+// returns a blank move if there is no expected reply
+func (pos *Position) getPonderMove() Move {
+	if pos.bestMove == BLANK_MOVE {
+		return BLANK_MOVE
+	}
+
+	if len(pos.pvLine) >= 2 && pos.pvLine[0] == pos.bestMove {
+		return pos.pvLine[1]
+	}
+
+	if pos.tt == nil {
+		return BLANK_MOVE
+	}
+
+	// play the best move and look up the hash move of the resulting position
+	ponderMove := BLANK_MOVE
+	pos.makeMove(pos.bestMove)
+
+	ttEntry, success := pos.tt.getTTEntry(pos.hashOfPos)
+	if success && ttEntry.move != BLANK_MOVE {
+
+		// the hash move could come from a different position with the same key, so make sure it is legal
+		pos.generateLegalMoves()
+		for _, move := range pos.threatMoves[:pos.threatMovesCounter] {
+			if move == ttEntry.move {
+				ponderMove = move
+			}
+		}
+		for _, move := range pos.quietMoves[:pos.quietMovesCounter] {
+			if move == ttEntry.move {
+				ponderMove = move
+			}
+		}
+	}
+
+	pos.undoMove()
+	return ponderMove
+}
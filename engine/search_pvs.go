@@ -0,0 +1,55 @@
+package main
+
+// --------------------------------------------------------------------------------------------------------------------
+// ----------------------------------------------- Principal Variation Search -----------------------------------------
+// --------------------------------------------------------------------------------------------------------------------
+/*
+With good move ordering, the first move searched at a node is very often the best move.
+Principal variation search (PVS) uses this to search the other moves faster:
+- The first move is searched with the full alpha-beta window.
+- All the other moves are searched with a null (zero width) window around alpha.
+  A null window search only proves whether the move is better than alpha or not, which is much cheaper than getting its exact value.
+- If the null window search shows that a move is better than alpha (but not already above beta), the move is better than expected.
+  We then search it again with the full window to get its exact value.
+
+At nodes that already have a null window (most nodes in the tree), the full window and the null window are the same,
+so there is only one search and never a re-search.
+We only use PVS at non-quiescence nodes, in quiescence we always search with the full window.
+
+The number of null window searches and re-searches are logged, to measure how often the first move was not the best move.
+*/
+
+// makes the move, searches it, and undoes the move
+// returns the value of the move from the point of view of the current node, and whether the search was terminated
+// movesSearched is the number of moves already searched at the current node (the first move is searched with the full window)
+func (pos *Position) searchMovePVS(move Move, initialDepth int, newDepth int, alpha int, beta int, tt *TranspositionTable, ht *HistoryTable,
+	qsDepth int, ply int, movesSearched int, nodeType int) (int, bool) {
+
+	pos.makeMove(move)
+
+	// ___ Full Window ___
+	// the first move, qs nodes and nodes that already have a null window are searched with the full window
+	if movesSearched == 0 || newDepth <= 0 || beta-alpha <= 1 {
+		score, terminated := pos.negamax(initialDepth, newDepth, 0-beta, 0-alpha, tt, ht, qsDepth, ply, false, BLANK_MOVE)
+		pos.undoMove()
+		return 0 - score, terminated
+	}
+
+	// ___ Null Window ___
+	pos.logSearch.depthLogs[nodeType].pvsNullWindowSearches++
+
+	score, terminated := pos.negamax(initialDepth, newDepth, 0-(alpha+1), 0-alpha, tt, ht, qsDepth, ply, false, BLANK_MOVE)
+	moveValue := 0 - score
+
+	// ___ Re-Search ___
+	// the move is better than expected, so we need its exact value with the full window
+	if !terminated && moveValue > alpha && moveValue < beta {
+		pos.logSearch.depthLogs[nodeType].pvsReSearches++
+
+		score, terminated = pos.negamax(initialDepth, newDepth, 0-beta, 0-alpha, tt, ht, qsDepth, ply, false, BLANK_MOVE)
+		moveValue = 0 - score
+	}
+
+	pos.undoMove()
+	return moveValue, terminated
+}
@@ -0,0 +1,190 @@
+package main
+
+// --------------------------------------------------------------------------------------------------------------------
+// ---------------------------------------------- Static Exchange Evaluation ------------------------------------------
+// --------------------------------------------------------------------------------------------------------------------
+/*
+Static exchange evaluation (SEE) calculates the material result of all the captures on one square,
+without searching and without making any moves.
+
+For example, a knight capturing a pawn that is defended by another pawn:
+- We win a pawn (+100), and they recapture the knight with the pawn (-400).
+- The exchange is therefore -300 for us, and the capture is a "bad" capture.
+Just comparing the captured and capturing pieces (MVV-LVA) would only tell us that the capture might lose material,
+SEE tells us whether it actually does.
+
+How it works (the "swap" algorithm):
+- We make the capture, and then both sides keep recapturing on the square with their least valuable attacker.
+- After each capture, the side to move can also decide to stop capturing, if recapturing would lose material.
+- We store the material balance after each capture in a list, and then go backwards through the list,
+  where each side picks the best of stopping or continuing the exchange.
+
+X-ray attackers:
+Each time a piece captures on the square, it is removed from the occupied squares.
+Sliding pieces behind it (for example a rook behind a rook on the same file) can then also attack the square.
+We find them by looking up the rook and bishop moves from the square again with the magic tables, with the updated occupied squares.
+
+Limitations (to keep SEE fast):
+- Pinned pieces are treated as if they can capture.
+- Pawns recapturing on the last rank are not counted as promoting.
+- The king only captures if the opponent has no more attackers left (it can't capture into check).
+*/
+
+const (
+	SEE_VALUE_KING int = 10000 // value of the king in SEE, so that capturing the king always ends the exchange
+)
+
+// the values of the pieces in SEE (indexed in the order K Q R N B P)
+var seePieceValues = [6]int{SEE_VALUE_KING, VALUE_QUEEN, VALUE_ROOK, VALUE_KNIGHT, VALUE_BISHOP, VALUE_PAWN}
+
+// the order in which the attackers recapture in SEE, from the least to the most valuable piece
+var seeAttackerOrder = [6]int{PIECE_PAWN, PIECE_KNIGHT, PIECE_BISHOP, PIECE_ROOK, PIECE_QUEEN, PIECE_KING}
+
+// returns the static exchange evaluation of a move for the side to move
+// a positive value means the move wins material, a negative value means the move loses material
+// quiet moves that are not promotions return the value of moving the piece to a square where it can be captured (zero or negative)
+func (pos *Position) getSEE(move Move) int {
+
+	// get the relevant move information
+	fromSq := move.getFromSq()
+	toSq := move.getToSq()
+	piece := move.getPiece()
+	moveType := move.getMoveType()
+	promotionType := move.getPromotionType()
+
+	side := SIDE_BLACK
+	enSide := SIDE_WHITE
+	if pos.isWhiteTurn {
+		side = SIDE_WHITE
+		enSide = SIDE_BLACK
+	}
+
+	occupied := pos.piecesAll[SIDE_BOTH]
+
+	// ___ First Capture ___
+	// the material won by the move itself
+	var gains [32]int
+	pieceOnSqValue := seePieceValues[piece]
+
+	if moveType == MOVE_TYPE_CAPTURE {
+		gains[0] = seePieceValues[pos.getPieceOnSq(enSide, toSq)]
+	} else if moveType == MOVE_TYPE_EN_PASSANT {
+		// the captured pawn is not on the target square, so we remove it from the occupied squares directly
+		gains[0] = VALUE_PAWN
+		if side == SIDE_WHITE {
+			occupied.clearBit(toSq - 8)
+		} else {
+			occupied.clearBit(toSq + 8)
+		}
+	}
+
+	if promotionType != PROMOTION_NONE {
+		gains[0] += seePieceValues[promotionType] - VALUE_PAWN
+		pieceOnSqValue = seePieceValues[promotionType]
+	}
+
+	// ___ Attackers ___
+	// the pieces of both sides attacking the square, after the moving piece left its square
+	occupied.clearBit(fromSq)
+	attackers := pos.getAttackersOfSq(toSq, occupied)
+
+	// ___ Exchanges ___
+	// both sides take turns to recapture on the square with their least valuable attacker
+	depth := 0
+	captureSide := enSide
+	for depth < len(gains)-1 {
+
+		attackers &= occupied
+		sideAttackers := attackers & pos.piecesAll[captureSide]
+		if sideAttackers == 0 {
+			break
+		}
+
+		// find the least valuable attacker
+		attackerPiece := PIECE_KING
+		var attackerBB Bitboard
+		for _, pieceType := range seeAttackerOrder {
+			attackerBB = sideAttackers & pos.pieces[captureSide][pieceType]
+			if attackerBB != 0 {
+				attackerPiece = pieceType
+				break
+			}
+		}
+
+		// the king can't capture if the opponent still attacks the square
+		if attackerPiece == PIECE_KING && (attackers&pos.piecesAll[1-captureSide]) != 0 {
+			break
+		}
+
+		// the material balance if the attacker captures (from the point of view of the capturing side)
+		depth++
+		gains[depth] = pieceOnSqValue - gains[depth-1]
+
+		// if both stopping and continuing are already losing for the capturing side, the result can't change anymore
+		if 0-gains[depth-1] < 0 && gains[depth] < 0 {
+			break
+		}
+
+		// remove the attacker from the occupied squares, and add any sliding pieces behind it (x-rays)
+		occupied.clearBit(attackerBB.getLSBSq())
+		if attackerPiece == PIECE_PAWN || attackerPiece == PIECE_BISHOP || attackerPiece == PIECE_QUEEN {
+			attackers |= getBishopMovesPseudo(toSq, occupied) & pos.getDiagonalSliders()
+		}
+		if attackerPiece == PIECE_ROOK || attackerPiece == PIECE_QUEEN {
+			attackers |= getRookMovesPseudo(toSq, occupied) & pos.getStraightSliders()
+		}
+
+		pieceOnSqValue = seePieceValues[attackerPiece]
+		captureSide = 1 - captureSide
+	}
+
+	// ___ Best Result ___
+	// go backwards through the exchanges, where each side can stop capturing if continuing would lose material
+	for depth > 0 {
+		if gains[depth] > 0-gains[depth-1] {
+			gains[depth-1] = 0 - gains[depth]
+		}
+		depth--
+	}
+
+	return gains[0]
+}
+
+// returns true if the capture (or promotion, or quiet move) does not lose material according to SEE
+func (pos *Position) isGoodCaptureSEE(move Move) bool {
+	return pos.getSEE(move) >= 0
+}
+
+// returns a bitboard with the pieces of both sides that attack the square, given the occupied squares
+func (pos *Position) getAttackersOfSq(sq int, occupied Bitboard) Bitboard {
+	attackers := getKnightMovesPseudo(sq) & (pos.pieces[SIDE_WHITE][PIECE_KNIGHT] | pos.pieces[SIDE_BLACK][PIECE_KNIGHT])
+	attackers |= getKingMovesPseudo(sq) & (pos.pieces[SIDE_WHITE][PIECE_KING] | pos.pieces[SIDE_BLACK][PIECE_KING])
+	attackers |= getBishopMovesPseudo(sq, occupied) & pos.getDiagonalSliders()
+	attackers |= getRookMovesPseudo(sq, occupied) & pos.getStraightSliders()
+	attackers |= movePawnsAttackingKingMasks[sq][SIDE_WHITE] & pos.pieces[SIDE_WHITE][PIECE_PAWN]
+	attackers |= movePawnsAttackingKingMasks[sq][SIDE_BLACK] & pos.pieces[SIDE_BLACK][PIECE_PAWN]
+	return attackers & occupied
+}
+
+// returns the bishops and queens of both sides
+func (pos *Position) getDiagonalSliders() Bitboard {
+	return pos.pieces[SIDE_WHITE][PIECE_BISHOP] | pos.pieces[SIDE_BLACK][PIECE_BISHOP] |
+		pos.pieces[SIDE_WHITE][PIECE_QUEEN] | pos.pieces[SIDE_BLACK][PIECE_QUEEN]
+}
+
+// returns the rooks and queens of both sides
+func (pos *Position) getStraightSliders() Bitboard {
+	return pos.pieces[SIDE_WHITE][PIECE_ROOK] | pos.pieces[SIDE_BLACK][PIECE_ROOK] |
+		pos.pieces[SIDE_WHITE][PIECE_QUEEN] | pos.pieces[SIDE_BLACK][PIECE_QUEEN]
+}
+
+// returns the type of the piece of the side on the square (PIECE_KING to PIECE_PAWN)
+// the square must contain a piece of the side
+func (pos *Position) getPieceOnSq(side int, sq int) int {
+	for pieceType := PIECE_KING; pieceType <= PIECE_PAWN; pieceType++ {
+		if pos.pieces[side][pieceType].isBitSet(sq) {
+			return pieceType
+		}
+	}
+	return PIECE_PAWN
+}
@@ -0,0 +1,64 @@
+package main
+
+// --------------------------------------------------------------------------------------------------------------------
+// ------------------------------------------------- Singular Extensions ----------------------------------------------
+// --------------------------------------------------------------------------------------------------------------------
+/*
+A move is "singular" if it is much better than all the other moves in the position.
+The position then depends on that one move, so we extend its search by 1 ply to search it more carefully.
+
+How we test whether the hash move is singular:
+- The TT entry of the node must be a lower bound (or exact) from a search that was not much shallower than the current depth,
+  so we have a reliable score that the hash move reaches (the TT value).
+- We search the node again at a reduced depth, with all moves except the hash move (the "excluded move"),
+  using a null window just below the singular beta (the TT value minus a margin that grows with the depth).
+- If no other move reaches the singular beta, the hash move is singular and we extend it.
+
+Multi-cut:
+If the exclusion search shows that another move also reaches the singular beta, and the singular beta is at or above beta,
+then at least two moves (the hash move and the other move) are expected to fail high.
+We then trust that the node fails high, and prune it by returning beta.
+
+The exclusion search searches the same position as the node itself, but without one move, so its results are different.
+To make sure these results are not mixed with the results of the full node in the TT,
+the TT key of an exclusion node is the position hash combined with a hash of the excluded move.
+Exclusion nodes don't do null move pruning, static null move pruning or another singular extension test.
+*/
+
+const (
+	SINGULAR_MIN_DEPTH             int = 6 // minimum depth of a node to test for a singular hash move
+	SINGULAR_TT_DEPTH_MARGIN       int = 3 // the TT entry depth must be at least the current depth minus this margin
+	SINGULAR_BETA_MARGIN_PER_DEPTH int = 3 // the singular beta is the TT value minus this margin times the depth
+
+	// multiplier to spread the bits of the excluded move over the whole TT key (the 64-bit golden ratio)
+	SINGULAR_EXCLUDED_MOVE_KEY_MULTIPLIER uint64 = 0x9e3779b97f4a7c15
+)
+
+// returns the key used to probe and store the node in the TT
+// for exclusion searches, the key is adjusted with the excluded move, so that exclusion nodes have their own TT entries
+func (pos *Position) getNodeHash(excludedMove Move) Bitboard {
+	if excludedMove == BLANK_MOVE {
+		return pos.hashOfPos
+	}
+	return pos.hashOfPos ^ Bitboard(uint64(excludedMove)*SINGULAR_EXCLUDED_MOVE_KEY_MULTIPLIER)
+}
+
+// returns the singular beta for the TT value at the depth
+func getSingularBeta(ttValue int, currentDepth int) int {
+	return ttValue - SINGULAR_BETA_MARGIN_PER_DEPTH*currentDepth
+}
+
+// returns the reduced depth of the exclusion search at the depth
+func getSingularSearchDepth(currentDepth int) int {
+	return (currentDepth - 1) / 2
+}
+
+// removes the excluded move from the moves, keeping the order of the remaining moves
+func removeExcludedMove(moves []Move, excludedMove Move) []Move {
+	for i, move := range moves {
+		if move == excludedMove {
+			return append(moves[:i], moves[i+1:]...)
+		}
+	}
+	return moves
+}
@@ -0,0 +1,105 @@
+package main
+
+// --------------------------------------------------------------------------------------------------------------------
+// ---------------------------------------------------- Lazy SMP ------------------------------------------------------
+// --------------------------------------------------------------------------------------------------------------------
+/*
+With the "Threads" uci option set above 1, the search uses Lazy SMP (symmetric multiprocessing):
+- The main thread does the normal iterative deepening search, and is the only thread that decides the best move.
+- Each helper thread searches the same position on its own goroutine, with its own copy of the position.
+- The helpers have their own killer moves and history table (the history table starts as a copy of the main thread's table).
+- All the threads share the same transposition table, which is the only way the threads help each other.
+
+The helpers don't communicate with the main thread during the search, they only store their results in the shared TT.
+The main thread then finds more TT hits (and better hash moves) in the parts of the tree that the helpers already searched.
+To make the threads search slightly different trees, every second helper starts its iterative deepening one depth deeper.
+
+The TT is written by all the threads at the same time without locks (see search_tt.go for how torn entries are detected).
+
+The helpers search until the main thread has finished its search (time, depth, node or mate limit, or a "stop" command).
+The main thread then tells the helpers to stop, and waits for them before sending the best move.
+The nodes searched by the helpers are added to a shared counter, so that the "info" lines report the nodes of all the threads.
+*/
+
+// starts the helper threads for the search (the number of threads includes the main thread)
+// must be called by the main thread once the TT and history table are ready for the search
+func (pos *Position) startHelperThreads(threads int) {
+	pos.searchControl.resetHelpers()
+
+	for helperIndex := 1; helperIndex < threads; helperIndex++ {
+		helperPos := pos.getHelperPosition()
+		index := helperIndex
+		pos.searchControl.startHelper(func() {
+			helperPos.searchAsHelper(index)
+		})
+	}
+}
+
+// tells the helper threads to stop, and waits until they have all stopped
+func (pos *Position) stopHelperThreads() {
+	pos.searchControl.stopHelpersAndWait()
+}
+
+// returns a copy of the position for a helper thread
+// the copy shares the TT and search control with the main thread, all other search tables are the helper's own
+func (pos *Position) getHelperPosition() *Position {
+	helperPos := new(Position)
+	*helperPos = *pos
+
+	helperPos.isHelperThread = true
+	helperPos.searchPrintInfo = false
+
+	// own history table (starting from what the main thread learned so far) and killer moves
+	helperHistoryTable := *pos.historyTable
+	helperPos.historyTable = &helperHistoryTable
+	helperPos.resetKillerMoveTable()
+
+	// own statistics
+	helperPos.logSearch = getNewSearchLogger()
+
+	// the helpers are only stopped by the main thread
+	helperPos.timeNodesCount = 0
+	helperPos.timeTotalAllowedTime = INFINITE_SEARCH_TIME_MS
+	helperPos.searchMaxNodes = 0
+	helperPos.searchPondering = false
+
+	// the helpers only search one line, following their own principal variation
+	helperPos.bestMoveSoFar = BLANK_MOVE
+	helperPos.pvLine = nil
+	helperPos.pvFollow = false
+	helperPos.pvFollowLine = nil
+	helperPos.multiPVLines = nil
+	helperPos.searchExcludedRootMoves = nil
+
+	return helperPos
+}
+
+// the iterative deepening search of a helper thread
+// the results are only stored in the shared TT, until the main thread tells the helper to stop
+func (pos *Position) searchAsHelper(helperIndex int) {
+
+	// every second helper starts one depth deeper
+	depth := MIN_SEARCH_DEPTH - 1 + (helperIndex % 2)
+
+	for depth < MAX_DEPTH {
+		depth += 1
+
+		// follow the helper's own principal variation of the previous iteration
+		pos.pvFollow = true
+
+		_, terminated := pos.negamax(depth, depth, 0-INFINITY, INFINITY, pos.tt, pos.historyTable, qsDepthLimitTable[depth], 0, false, BLANK_MOVE)
+		if terminated {
+			break
+		}
+
+		pos.pvFollowLine = pos.getRootPVLine()
+	}
+
+	// add the nodes searched since the last time check to the shared node count
+	pos.searchControl.addHelperNodes(pos.timeNodesCount)
+}
+
+// returns the nodes searched so far by the main thread and all the helper threads
+func (pos *Position) getSearchNodes() int {
+	return pos.logSearch.getTotalNodes() + pos.searchControl.getHelperNodes()
+}
@@ -1,5 +1,10 @@
 package main
 
+import (
+	"sync/atomic"
+	"unsafe"
+)
+
 // --------------------------------------------------------------------------------------------------------------------
 // -------------------------------------------- Transposition Table: Background ---------------------------------------
 // --------------------------------------------------------------------------------------------------------------------
@@ -13,17 +18,50 @@ Each node stored in the TT is a struct and needs to have information about:
 - Flag (specifying the type of bound we got on the search: exact, lower bound, upper bound)
 - Value (the negamax value from the previous search)
 - Move (the best move at the node from the previous search)
+- Age (the generation of the search that stored the entry)
+
+--------------------------------------------- TT Packed Entry ------------------------------------------------
+The TT is shared by all the search threads (see search_smp.go), and the threads read and write entries at the same time without locks.
+We therefore store each entry packed into 3 words, which are each read and written atomically:
+- Move and Value: the move (the lower 32 bits, without the move ordering score) and the value.
+- Depth, Flag and Age.
+- Key: the zobrist hash of the position XOR both data words above.
+
+Two threads can still write the same entry at the same time, and a reader can then see the words of 2 different entries mixed together.
+When reading an entry, we XOR the key word with both data words again.
+The result is only the hash of the position we are looking for if all 3 words come from the same write,
+so a mixed (torn) entry is treated as a TT miss instead of giving a wrong move or value.
 
-Each TT entry contains:
-- Hash: 1 x uint64 = 1 x 8 = 8 bytes.
-- Move: 1 x uint64 = 1 x 8 = 8 bytes.
-- Value: 1 x int32 = 1 x 4 = 4 bytes.
-- Depth: 1 x uint8 = 1 x 1 = 1 byte.
-- Flag: 1 x uint8 = 1 x 1 = 1 byte.
+Each TT entry contains 3 x uint64 = 3 x 8 = 24 bytes in memory.
 
-The TT index key also needs to be stored: 1 x uint32 (max value of 4bil) = 1 x 4 = 4 bytes.
+--------------------------------------------- TT Size ------------------------------------------------
+The size of the TT is set in MB with the "Hash" uci option, and the table is allocated on the heap at runtime.
+The number of entries is always a power of two (the largest that fits in the allowed memory),
+so that we can get the TT index with a simple mask of the hash, instead of a slower modulo operation.
 
-Therefore each TT entry is about 26 bytes.
+--------------------------------------------- TT Age ------------------------------------------------
+The TT is kept between searches (moves) of the same game, because most of the tree from the previous move is still relevant.
+It is only cleared when a new game starts ("ucinewgame") or with the "Clear Hash" uci option.
+Each search increases the TT generation, and each entry stores the generation of the search that saved it.
+When storing a new entry, we always replace entries from older searches, but we keep deeper entries from the current search.
+
+--------------------------------------------- TT QS Entries ------------------------------------------------
+Quiescence nodes are also stored in the TT, in 2 tiers below the depth of any non-quiescence node:
+- QS without checks (depth below 0): only captures (or evasions) are searched.
+- QS with checks (depth 0): quiet checks are also searched, so it is a bigger search than QS without checks.
+Within each tier, the stored depth is the remaining distance to the qs depth limit of the search (qsDepth),
+because a node close to the limit (for example from an earlier iteration with a lower limit) searched less than a node far from it.
+The stored depths of the tiers and the non-quiescence nodes never overlap:
+- QS without checks: 1 to TT_DEPTH_QS_CHECKS (exclusive).
+- QS with checks: TT_DEPTH_QS_CHECKS + 1 to TT_DEPTH_FULL_WIDTH (inclusive).
+- Non-quiescence nodes: TT_DEPTH_FULL_WIDTH + the remaining depth.
+Entries of non-quiescence nodes are always deep enough to give a cutoff at a quiescence node, but never the other way around.
+A QS with checks entry is deep enough for a QS without checks node with the same or less remaining distance,
+but a QS without checks entry never gives a cutoff at a QS with checks node (see isTTDepthEnough).
+When the stand pat score fails high, the node is stored as a lower bound (beta).
+When the stand pat score raises alpha and no capture improves on it, the stand pat score is the exact value of the node.
+A quiescence entry never replaces a deeper entry of the same position from the current search,
+so that quiescence nodes don't overwrite the more valuable entries (and hash moves) of the full width search.
 */
 
 // --------------------------------------------------------------------------------------------------------------------
@@ -34,6 +72,10 @@ const (
 	TT_FLAG_EXACT      uint8 = 0
 	TT_FLAG_UPPERBOUND uint8 = 1
 	TT_FLAG_LOWERBOUND uint8 = 2
+
+	// the stored depth tiers (see the TT QS entries background above)
+	TT_DEPTH_QS_CHECKS  uint8 = uint8(0 - MIN_QS_DEPTH)                 // QS with checks entries are stored above this depth
+	TT_DEPTH_FULL_WIDTH uint8 = TT_DEPTH_QS_CHECKS + TT_DEPTH_QS_CHECKS // non-quiescence entries are stored above this depth
 )
 
 type TTEntry struct {
@@ -42,6 +84,61 @@ type TTEntry struct {
 	value       int32    // negamax search value
 	depth       uint8    // depth of the search
 	flag        uint8    // exact, lower or upperbound
+	age         uint8    // generation of the search that stored the entry
+}
+
+// ___ Packed Entry ___
+
+const (
+	TT_PACKED_SHIFT_VALUE int = 32 // the value is stored after the move in the move and value word
+	TT_PACKED_SHIFT_FLAG  int = 8  // the flag is stored after the depth in the depth, flag and age word
+	TT_PACKED_SHIFT_AGE   int = 16 // the age is stored after the flag in the depth, flag and age word
+
+	TT_PACKED_MASK_32_BITS uint64 = 0xffffffff
+	TT_PACKED_MASK_8_BITS  uint64 = 0xff
+)
+
+type TTPackedEntry struct {
+	key          uint64 // zobrist hash XOR both data words
+	moveAndValue uint64 // move in the lower 32 bits, value in the upper 32 bits
+	depthAndFlag uint64 // depth, flag and age (8 bits each)
+}
+
+// packs the entry into the 3 words stored in the TT
+func (entry *TTEntry) pack() TTPackedEntry {
+	moveAndValue := (uint64(entry.move) & TT_PACKED_MASK_32_BITS) | (uint64(uint32(entry.value)) << TT_PACKED_SHIFT_VALUE)
+	depthAndFlag := uint64(entry.depth) | (uint64(entry.flag) << TT_PACKED_SHIFT_FLAG) | (uint64(entry.age) << TT_PACKED_SHIFT_AGE)
+	key := uint64(entry.zobristHash) ^ moveAndValue ^ depthAndFlag
+	return TTPackedEntry{key, moveAndValue, depthAndFlag}
+}
+
+// unpacks the 3 words stored in the TT into an entry
+// the hash of the entry is only correct if all 3 words were stored by the same write
+func (packed *TTPackedEntry) unpack() TTEntry {
+	return TTEntry{
+		zobristHash: Bitboard(packed.key ^ packed.moveAndValue ^ packed.depthAndFlag),
+		move:        Move(packed.moveAndValue & TT_PACKED_MASK_32_BITS),
+		value:       int32(uint32(packed.moveAndValue >> TT_PACKED_SHIFT_VALUE)),
+		depth:       uint8(packed.depthAndFlag & TT_PACKED_MASK_8_BITS),
+		flag:        uint8((packed.depthAndFlag >> TT_PACKED_SHIFT_FLAG) & TT_PACKED_MASK_8_BITS),
+		age:         uint8((packed.depthAndFlag >> TT_PACKED_SHIFT_AGE) & TT_PACKED_MASK_8_BITS),
+	}
+}
+
+// reads the 3 words of the packed entry atomically (each word on its own)
+func (packed *TTPackedEntry) atomicLoad() TTPackedEntry {
+	return TTPackedEntry{
+		key:          atomic.LoadUint64(&packed.key),
+		moveAndValue: atomic.LoadUint64(&packed.moveAndValue),
+		depthAndFlag: atomic.LoadUint64(&packed.depthAndFlag),
+	}
+}
+
+// writes the 3 words of the packed entry atomically (each word on its own)
+func (packed *TTPackedEntry) atomicStore(newPacked TTPackedEntry) {
+	atomic.StoreUint64(&packed.key, newPacked.key)
+	atomic.StoreUint64(&packed.moveAndValue, newPacked.moveAndValue)
+	atomic.StoreUint64(&packed.depthAndFlag, newPacked.depthAndFlag)
 }
 
 // --------------------------------------------------------------------------------------------------------------------
@@ -49,39 +146,171 @@ type TTEntry struct {
 // --------------------------------------------------------------------------------------------------------------------
 
 const (
-	TT_SIZE_IN_MB              int      = 12
-	TT_SIZE_PER_ENTRY_IN_BYTES int      = 26
-	TT_SIZE_MAX                int      = TT_SIZE_IN_MB * 1024 * 1024 / TT_SIZE_PER_ENTRY_IN_BYTES
-	TT_SIZE_MAX_BB             Bitboard = Bitboard(TT_SIZE_MAX)
+	TT_SIZE_DEFAULT_IN_MB      int = 12 // default size of the "Hash" uci option
+	TT_SIZE_PER_ENTRY_IN_BYTES int = int(unsafe.Sizeof(TTPackedEntry{}))
 )
 
-type TTKey uint32
+type TTKey uint64
 
 type TranspositionTable struct {
-	entries [TT_SIZE_MAX]TTEntry
+	entries    []TTPackedEntry // all entries in the TT (the length is a power of two)
+	mask       Bitboard        // length of the entries less 1, used to get the TT index from a hash
+	sizeInMB   int             // size of the TT as requested in MB
+	generation uint8           // generation of the current search, increased at the start of every search
 }
 
-// returns a newly created TT with a pre-allocated maximum size
-func getNewTT() *TranspositionTable {
+// returns a newly created TT with the given size in MB
+func getNewTT(sizeInMB int) *TranspositionTable {
 	newTT := TranspositionTable{}
+	newTT.resize(sizeInMB)
 	return &newTT
 }
 
-// this will give TT index keys from 0 (inclusive) to TT_SIZE_MAX (exclusive)
-func getTTKeyFromPosHash(posHash Bitboard) TTKey {
-	return TTKey(posHash % TT_SIZE_MAX_BB)
+// allocates the TT entries again for the given size in MB, all previous entries are lost
+// the number of entries is the largest power of two that fits in the given size
+func (tt *TranspositionTable) resize(sizeInMB int) {
+
+	// release the previous entries first, so that both the old and new table don't need to be in memory at the same time
+	tt.entries = nil
+
+	maxEntries := sizeInMB * 1024 * 1024 / TT_SIZE_PER_ENTRY_IN_BYTES
+	totalEntries := 1
+	for totalEntries*2 <= maxEntries {
+		totalEntries *= 2
+	}
+
+	tt.entries = make([]TTPackedEntry, totalEntries)
+	tt.mask = Bitboard(totalEntries - 1)
+	tt.sizeInMB = sizeInMB
+}
+
+// clears all the entries in the TT, without changing the size
+// this must only be called when no search is running
+func (tt *TranspositionTable) clear() {
+	for i := range tt.entries {
+		tt.entries[i] = TTPackedEntry{}
+	}
+	tt.generation = 0
+}
+
+// increases the generation at the start of a new search, so that entries from previous searches are replaced first
+// the generation wraps around after 256 searches, which is fine because it is only compared for equality
+// this must be called before the search threads start, because the threads read the generation without locks
+func (tt *TranspositionTable) newSearch() {
+	tt.generation += 1
+}
+
+// this will give TT index keys from 0 (inclusive) to the number of entries (exclusive)
+func (tt *TranspositionTable) getTTKeyFromPosHash(posHash Bitboard) TTKey {
+	return TTKey(posHash & tt.mask)
+}
+
+// returns the depth stored in the TT for a node at the current depth
+// quiescence nodes are stored in their tier with the remaining distance to the qs depth limit
+func getTTDepth(currentDepth int, qsDepth int) uint8 {
+	if currentDepth > 0 {
+		return TT_DEPTH_FULL_WIDTH + uint8(currentDepth)
+	}
+
+	qsRemaining := uint8(currentDepth - qsDepth)
+	if currentDepth == 0 {
+		return TT_DEPTH_QS_CHECKS + qsRemaining
+	}
+	return qsRemaining
+}
+
+// returns true if a TT entry with the stored depth was searched at least as deep as a node with the needed depth
+func isTTDepthEnough(entryDepth uint8, neededDepth uint8) bool {
+
+	// a QS without checks entry never replaces the search of a QS with checks node
+	if neededDepth > TT_DEPTH_QS_CHECKS && entryDepth <= TT_DEPTH_QS_CHECKS {
+		return false
+	}
+
+	// a QS with checks entry covers a QS without checks node with the same or less remaining distance
+	if neededDepth <= TT_DEPTH_QS_CHECKS && entryDepth > TT_DEPTH_QS_CHECKS && entryDepth <= TT_DEPTH_FULL_WIDTH {
+		return entryDepth-TT_DEPTH_QS_CHECKS >= neededDepth
+	}
+
+	return entryDepth >= neededDepth
 }
 
 // this will store a new TT entry with the provided values
+// we replace the existing entry when:
+// - it is from a previous search (or empty)
+// - it is for the same position (newer information), unless a quiescence entry would replace a deeper entry
+// - the new entry was searched at least as deep
 func (tt *TranspositionTable) storeNewTTEntry(zobristHashToStore Bitboard, move Move, value int32, depth uint8, flag uint8) {
-	ttKey := getTTKeyFromPosHash(zobristHashToStore)
-	newTTEntry := TTEntry{zobristHashToStore, move, value, depth, flag}
-	tt.entries[ttKey] = newTTEntry
+	ttKey := tt.getTTKeyFromPosHash(zobristHashToStore)
+	existingPacked := tt.entries[ttKey].atomicLoad()
+	existingEntry := existingPacked.unpack()
+
+	samePosition := existingEntry.zobristHash == zobristHashToStore
+	qsReplacesDeeper := depth <= TT_DEPTH_FULL_WIDTH && existingEntry.depth > depth
+	if existingEntry.age != tt.generation || (samePosition && !qsReplacesDeeper) || depth >= existingEntry.depth {
+
+		// keep the previous best move for the same position if we don't have a new best move (upper bound nodes)
+		if move == BLANK_MOVE && samePosition {
+			move = existingEntry.move
+		}
+
+		newTTEntry := TTEntry{zobristHashToStore, move, value, depth, flag, tt.generation}
+		tt.entries[ttKey].atomicStore(newTTEntry.pack())
+	}
 }
 
 // this will search the TT for a given hash, and return the TT bucket and success flag
 func (tt *TranspositionTable) getTTEntry(zobristHashToGet Bitboard) (TTEntry, bool) {
-	ttEntry := tt.entries[getTTKeyFromPosHash(zobristHashToGet)]
+	packed := tt.entries[tt.getTTKeyFromPosHash(zobristHashToGet)].atomicLoad()
+	ttEntry := packed.unpack()
 	success := ttEntry.zobristHash == zobristHashToGet
 	return ttEntry, success
 }
+
+// --------------------------------------------------------------------------------------------------------------------
+// ------------------------------------------------------ TT Setup ----------------------------------------------------
+// --------------------------------------------------------------------------------------------------------------------
+
+// makes sure the position has a TT with the size from the "Hash" uci option
+// the TT is only allocated again if the size changed
+func (pos *Position) resizeTTFromOptions() {
+	sizeInMB := getUCIOptionSpinValue(UCI_OPTION_HASH)
+	if pos.tt == nil {
+		pos.tt = getNewTT(sizeInMB)
+	} else if pos.tt.sizeInMB != sizeInMB {
+		pos.tt.resize(sizeInMB)
+	}
+}
+
+// clears the tables learned during previous searches: the TT and the history table (if they were already created)
+// this is done when a new game starts, or with the "Clear Hash" uci option
+func (pos *Position) clearSearchTables() {
+	if pos.tt != nil {
+		pos.tt.clear()
+	}
+	pos.historyTable = getNewHistoryTable()
+}
+
+// --------------------------------------------------------------------------------------------------------------------
+// ------------------------------------------------------ TT Usage ----------------------------------------------------
+// --------------------------------------------------------------------------------------------------------------------
+
+// returns how full the TT is in permill (as sent to the GUI with "info hashfull")
+// we only sample the first 1000 entries, and only count entries stored during the current search
+func (tt *TranspositionTable) getHashFull() int {
+	sampleSize := 1000
+	if len(tt.entries) < sampleSize {
+		sampleSize = len(tt.entries)
+	}
+
+	usedEntries := 0
+	for i := range tt.entries[:sampleSize] {
+		packed := tt.entries[i].atomicLoad()
+		entry := packed.unpack()
+		if packed.key != 0 && entry.age == tt.generation {
+			usedEntries += 1
+		}
+	}
+
+	return usedEntries * 1000 / sampleSize
+}